@@ -0,0 +1,215 @@
+package responsewriter
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrNoCredentials is returned by authenticators when the request carries no credentials
+// they understand, the next authenticator is tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated identity of a request.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Roles   []string
+	// Details holds authenticator specific information, e.g. token claims
+	Details interface{}
+}
+
+// Authenticator authenticates requests, returning ErrNoCredentials when the request does not carry
+// its kind of credentials and another error when the credentials are invalid.
+type Authenticator interface {
+	Authenticate(r *Request) (*Principal, error)
+	// Challenge is the WWW-Authenticate value of a 401 response, empty for none
+	Challenge() string
+}
+
+// AuthenticatorFunc is a custom Authenticator.
+type AuthenticatorFunc struct {
+	Func           func(r *Request) (*Principal, error)
+	ChallengeValue string
+}
+
+func (af AuthenticatorFunc) Authenticate(r *Request) (*Principal, error) {
+	return af.Func(r)
+}
+
+func (af AuthenticatorFunc) Challenge() string {
+	return af.ChallengeValue
+}
+
+// BearerAuth authenticates the token of the Authorization: Bearer header
+func BearerAuth(realm string, validate func(ctx context.Context, token string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc{
+		Func: func(r *Request) (*Principal, error) {
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+
+			if !ok || !strings.EqualFold(scheme, "Bearer") || len(token) == 0 {
+				return nil, ErrNoCredentials
+			}
+
+			return validate(r.Context(), strings.TrimSpace(token))
+		},
+		ChallengeValue: `Bearer realm="` + realm + `"`,
+	}
+}
+
+// BasicAuth authenticates the user and password of the Authorization: Basic header
+func BasicAuth(realm string, validate func(ctx context.Context, user, password string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc{
+		Func: func(r *Request) (*Principal, error) {
+			user, password, ok := r.BasicAuth()
+
+			if !ok {
+				return nil, ErrNoCredentials
+			}
+
+			return validate(r.Context(), user, password)
+		},
+		ChallengeValue: `Basic realm="` + realm + `", charset="UTF-8"`,
+	}
+}
+
+// APIKeyAuth authenticates the key in the header
+func APIKeyAuth(header string, validate func(ctx context.Context, key string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc{
+		Func: func(r *Request) (*Principal, error) {
+			key := r.Header.Get(header)
+
+			if len(key) == 0 {
+				return nil, ErrNoCredentials
+			}
+
+			return validate(r.Context(), key)
+		},
+	}
+}
+
+// StaticAPIKeys validates API keys against a fixed set, mapping keys to their principal
+func StaticAPIKeys(keys map[string]*Principal) func(ctx context.Context, key string) (*Principal, error) {
+	return func(_ context.Context, key string) (*Principal, error) {
+		for k, p := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return p, nil
+			}
+		}
+
+		return nil, errors.New("invalid api key")
+	}
+}
+
+// Authenticate tries the authenticators in order and attaches the principal of the first one
+// succeeding to the Request. Without valid credentials it answers 401 Unauthorized, with the
+// challenges of the authenticators as WWW-Authenticate headers.
+func Authenticate(authenticators ...Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			var err error
+
+			for _, a := range authenticators {
+				var p *Principal
+
+				p, err = a.Authenticate(r)
+
+				if err == nil && p != nil {
+					r.principal = p
+
+					return next(r)
+				}
+
+				if err != nil && !errors.Is(err, ErrNoCredentials) {
+					break
+				}
+			}
+
+			if err == nil {
+				err = ErrNoCredentials
+			}
+
+			return unauthorized(authenticators, err)
+		}
+	}
+}
+
+// RequireScopes answers 403 Forbidden unless the principal has all scopes, 401 without principal
+func RequireScopes(scopes ...string) Middleware {
+	return require(func(p *Principal) bool { return containsAll(p.Scopes, scopes) })
+}
+
+// RequireRoles answers 403 Forbidden unless the principal has any of the roles, 401 without principal
+func RequireRoles(roles ...string) Middleware {
+	return require(func(p *Principal) bool { return containsAny(p.Roles, roles) })
+}
+
+func require(allowed func(p *Principal) bool) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			if r.principal == nil {
+				return unauthorized(nil, ErrNoCredentials)
+			}
+
+			if !allowed(r.principal) {
+				return errorReply(http.StatusForbidden, http.StatusText(http.StatusForbidden), nil)
+			}
+
+			return next(r)
+		}
+	}
+}
+
+// Principal returns the authenticated principal, nil when not authenticated
+func (r *Request) Principal() *Principal {
+	return r.principal
+}
+
+// PrincipalDetails returns the details of the authenticated principal as T
+func PrincipalDetails[T any](r *Request) (T, bool) {
+	var zero T
+
+	if r.principal == nil {
+		return zero, false
+	}
+
+	d, ok := r.principal.Details.(T)
+
+	return d, ok
+}
+
+func unauthorized(authenticators []Authenticator, err error) *Reply {
+	reply := errorReply(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), err)
+
+	for _, a := range authenticators {
+		if c := a.Challenge(); len(c) > 0 {
+			reply.Header("WWW-Authenticate", c)
+		}
+	}
+
+	return reply
+}
+
+func containsAll(values, required []string) bool {
+	for _, r := range required {
+		if !containsAny(values, []string{r}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if v == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
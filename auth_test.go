@@ -0,0 +1,120 @@
+package responsewriter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+type tokenClaims struct {
+	Tenant string
+}
+
+func validateToken(_ context.Context, token string) (*Principal, error) {
+	switch token {
+	case "admin":
+		return &Principal{Subject: "admin", Scopes: []string{"read", "write"}, Roles: []string{"admin"}, Details: tokenClaims{Tenant: "a"}}, nil
+	case "reader":
+		return &Principal{Subject: "reader", Scopes: []string{"read"}}, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+func validateBasic(_ context.Context, user, password string) (*Principal, error) {
+	if user == "user" && password == "secret" {
+		return &Principal{Subject: user}, nil
+	}
+
+	return nil, errors.New("invalid password")
+}
+
+func authRouter() *Router {
+	rt := NewRouter(responsetype.TypeJSON).Use(Authenticate(
+		BearerAuth("api", validateToken),
+		BasicAuth("api", validateBasic),
+		APIKeyAuth("X-API-Key", StaticAPIKeys(map[string]*Principal{"key": {Subject: "service"}})),
+	))
+
+	rt.GET("/me", func(r *Request) interface{} {
+		claims, _ := PrincipalDetails[tokenClaims](r)
+
+		return []string{r.Principal().Subject, claims.Tenant}
+	})
+
+	rt.POST("/items", func(r *Request) interface{} { return nil }, RequireScopes("write"))
+	rt.DELETE("/items", func(r *Request) interface{} { return nil }, RequireRoles("admin", "owner"))
+
+	return rt
+}
+
+func TestAuthenticate(t *testing.T) {
+	rt := authRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		code   int
+		body   string
+	}{
+		{"bearer", http.MethodGet, "/me", map[string]string{"Authorization": "Bearer admin"}, http.StatusOK, `["admin","a"]`},
+		{"basic", http.MethodGet, "/me", map[string]string{"Authorization": "Basic dXNlcjpzZWNyZXQ="}, http.StatusOK, `["user",""]`},
+		{"api key", http.MethodGet, "/me", map[string]string{"X-API-Key": "key"}, http.StatusOK, `["service",""]`},
+		{"missing", http.MethodGet, "/me", nil, http.StatusUnauthorized, `{"code":401,"description":"Unauthorized"}`},
+		{"invalid token", http.MethodGet, "/me", map[string]string{"Authorization": "Bearer other"}, http.StatusUnauthorized, `{"code":401,"description":"Unauthorized"}`},
+		{"invalid key", http.MethodGet, "/me", map[string]string{"X-API-Key": "other"}, http.StatusUnauthorized, `{"code":401,"description":"Unauthorized"}`},
+		{"scope", http.MethodPost, "/items", map[string]string{"Authorization": "Bearer admin"}, http.StatusNoContent, ""},
+		{"missing scope", http.MethodPost, "/items", map[string]string{"Authorization": "Bearer reader"}, http.StatusForbidden, `{"code":403,"description":"Forbidden"}`},
+		{"role", http.MethodDelete, "/items", map[string]string{"Authorization": "Bearer admin"}, http.StatusNoContent, ""},
+		{"missing role", http.MethodDelete, "/items", map[string]string{"Authorization": "Bearer reader"}, http.StatusForbidden, `{"code":403,"description":"Forbidden"}`},
+	}
+
+	for _, test := range tests {
+		w := serveCORS(rt, test.method, test.path, test.header)
+
+		if w.Code != test.code {
+			t.Errorf("Invalid status code for %s, expected %d, got %d", test.name, test.code, w.Code)
+		}
+
+		if b := w.Body.String(); b != test.body {
+			t.Errorf("Invalid body for %s, expected %s, got %s", test.name, test.body, b)
+		}
+	}
+
+	w := serveCORS(rt, http.MethodGet, "/me", nil)
+
+	challenges := w.Header().Values("WWW-Authenticate")
+
+	if len(challenges) != 2 || challenges[0] != `Bearer realm="api"` || challenges[1] != `Basic realm="api", charset="UTF-8"` {
+		t.Errorf("Invalid challenges, got %v", challenges)
+	}
+}
+
+func TestRequire_WithoutPrincipal(t *testing.T) {
+	fn := RequireScopes("read")(func(r *Request) interface{} { return nil })
+
+	res := fn(NewRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil))
+
+	if reply, ok := res.(*Reply); !ok || reply.GetCode() != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 reply, got %#v", res)
+	}
+}
+
+func TestPrincipalDetails(t *testing.T) {
+	r := NewRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+
+	if _, ok := PrincipalDetails[tokenClaims](r); ok {
+		t.Errorf("Expected no details without principal")
+	}
+
+	r.principal = &Principal{Details: "other"}
+
+	if _, ok := PrincipalDetails[tokenClaims](r); ok {
+		t.Errorf("Expected no details of another type")
+	}
+}
@@ -0,0 +1,290 @@
+package responsewriter
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cacheable is implemented by return values which may be cached by the Cache middleware,
+// e.g. a Reply with Cache.
+type Cacheable interface {
+	CacheTTL() time.Duration
+}
+
+// CachedResponse is a response stored by the Cache middleware.
+type CachedResponse struct {
+	Code    int
+	Header  http.Header
+	Body    []byte
+	Stored  time.Time
+	Expires time.Time
+}
+
+// CacheStore stores encoded responses, implementations backed by a shared store
+// allow caching across instances.
+type CacheStore interface {
+	// Get returns the response of the key, nil when missing or expired
+	Get(ctx context.Context, key string) (*CachedResponse, error)
+	Set(ctx context.Context, key string, resp *CachedResponse) error
+}
+
+// Cache serves GET and HEAD requests from the store, keyed by method, path, query,
+// negotiated media type and the values of the vary request headers. Only successful
+// responses of handlers returning a Cacheable value are stored.
+//
+// The Cache-Control request directives no-store, no-cache and max-age are honoured.
+// Add the middleware after authentication, cached responses are served to any
+// request reaching it.
+func Cache(store CacheStore, vary ...string) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				return next(r)
+			}
+
+			cc := parseRequestCacheControl(r.Header.Get("Cache-Control"))
+
+			if cc.noStore {
+				return next(r)
+			}
+
+			key := cacheKey(r, vary)
+
+			if !cc.noCache {
+				cr, err := store.Get(r.Context(), key)
+
+				if err != nil {
+					r.logger().WithError(err).Error("Failed to read response cache, serving request")
+				} else if cr != nil && cc.accepts(cr.Stored) {
+					return &cacheHit{resp: cr}
+				}
+			}
+
+			resp := next(r)
+
+			if c, ok := resp.(Cacheable); ok && c.CacheTTL() > 0 {
+				r.cache = &cacheCapture{store: store, key: key, ttl: c.CacheTTL()}
+			}
+
+			return resp
+		}
+	}
+}
+
+// cacheKey identifies the response of a request
+func cacheKey(r *Request, vary []string) string {
+	parts := []string{r.Method, r.URL.Path, r.URL.Query().Encode()}
+
+	if r.responseType != nil {
+		parts = append(parts, r.responseType.GetAcceptedType())
+	}
+
+	for _, h := range vary {
+		parts = append(parts, h+":"+strings.Join(r.Header.Values(h), ","))
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+type requestCacheControl struct {
+	noStore bool
+	noCache bool
+	maxAge  time.Duration
+	hasAge  bool
+}
+
+func parseRequestCacheControl(v string) requestCacheControl {
+	cc := requestCacheControl{}
+
+	for _, d := range strings.Split(v, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "max-age":
+			if s, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && s >= 0 {
+				cc.maxAge = time.Duration(s) * time.Second
+				cc.hasAge = true
+			}
+		}
+	}
+
+	return cc
+}
+
+// accepts reports whether a response stored at the time is fresh enough for the request,
+// the age is counted in whole seconds like the Age header
+func (cc requestCacheControl) accepts(stored time.Time) bool {
+	return !cc.hasAge || time.Since(stored).Truncate(time.Second) <= cc.maxAge
+}
+
+// cacheHit is returned by the middleware to serve a stored response
+type cacheHit struct {
+	resp *CachedResponse
+}
+
+// write writes the stored response, headers added by middleware for this request take precedence
+func (ch *cacheHit) write(w http.ResponseWriter, r *Request) {
+	writeStoredHeader(w, ch.resp.Header, r.header)
+
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(ch.resp.Stored).Seconds())))
+	w.WriteHeader(ch.resp.Code)
+
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(ch.resp.Body)
+	}
+}
+
+// cacheCapture stores the response of a cacheable request once written
+type cacheCapture struct {
+	store CacheStore
+	key   string
+	ttl   time.Duration
+
+	recorder *responseRecorder
+}
+
+func (cc *cacheCapture) record(w http.ResponseWriter) http.ResponseWriter {
+	cc.recorder = &responseRecorder{ResponseWriter: w}
+
+	return cc.recorder
+}
+
+// complete stores successful responses, without the headers added by middleware for this request.
+// Responses which are private to the client, e.g. setting a cookie, are not stored.
+func (cc *cacheCapture) complete(r *Request) {
+	rec := cc.recorder
+
+	if rec.code < http.StatusOK || rec.code >= http.StatusMultipleChoices || rec.code == http.StatusPartialContent {
+		return
+	}
+
+	if private(rec.header) {
+		return
+	}
+
+	now := time.Now()
+
+	err := cc.store.Set(r.Context(), cc.key, &CachedResponse{
+		Code:    rec.code,
+		Header:  storedHeader(rec.header, r.header),
+		Body:    rec.body.Bytes(),
+		Stored:  now,
+		Expires: now.Add(cc.ttl),
+	})
+
+	if err != nil {
+		r.logger().WithError(err).Error("Failed to store response in cache")
+	}
+}
+
+// private reports whether the response may not be stored by a shared cache
+func private(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return true
+	}
+
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+
+			if strings.EqualFold(name, "private") || strings.EqualFold(name, "no-store") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// LRUCache is an in memory CacheStore, evicting the least recently used response
+// when full and expired responses when read.
+type LRUCache struct {
+	capacity int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		panic("Invalid LRU cache capacity")
+	}
+
+	return &LRUCache{
+		capacity: capacity,
+		now:      time.Now,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(_ context.Context, key string) (*CachedResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	e := el.Value.(*lruEntry)
+
+	if !c.now().Before(e.resp.Expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+
+		return nil, nil
+	}
+
+	c.ll.MoveToFront(el)
+
+	return e.resp, nil
+}
+
+func (c *LRUCache) Set(_ context.Context, key string, resp *CachedResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).resp = resp
+		c.ll.MoveToFront(el)
+
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, resp: resp})
+
+	for c.ll.Len() > c.capacity {
+		el := c.ll.Back()
+
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of stored responses
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
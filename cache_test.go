@@ -0,0 +1,179 @@
+package responsewriter
+
+import (
+	"context"
+	"net/http"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+
+	store := NewLRUCache(10)
+
+	rt := NewRouter(responsetype.TypeJSON, responsetype.TypeCSV)
+
+	rt.GET("/items", func(r *Request) interface{} {
+		calls++
+
+		if r.URL.Query().Get("uncached") == "1" {
+			return []int{calls}
+		}
+
+		r.ResponseHeader().Set("X-Call", strconv.Itoa(calls))
+
+		return Respond(http.StatusOK, []int{calls}).Header("X-Test", "a").Cache(time.Minute)
+	}, Cache(store, "Accept-Language"))
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		body   string
+		calls  int
+	}{
+		{"miss", "/items?a=1&b=2", nil, "[1]", 1},
+		{"hit", "/items?b=2&a=1", nil, "[1]", 1},
+		{"media type", "/items?a=1&b=2", map[string]string{"Accept": "text/csv"}, "2\n", 2},
+		{"media type hit", "/items?a=1&b=2", map[string]string{"Accept": "text/csv"}, "2\n", 2},
+		{"vary", "/items?a=1&b=2", map[string]string{"Accept-Language": "nl"}, "[3]", 3},
+		{"no-cache", "/items?a=1&b=2", map[string]string{"Cache-Control": "no-cache"}, "[4]", 4},
+		{"refreshed", "/items?a=1&b=2", nil, "[4]", 4},
+		{"no-store", "/items?a=1&b=2", map[string]string{"Cache-Control": "no-store"}, "[5]", 5},
+		{"max-age", "/items?a=1&b=2", map[string]string{"Cache-Control": "max-age=0"}, "[4]", 5},
+		{"not cacheable", "/items?uncached=1", nil, "[6]", 6},
+		{"not cacheable again", "/items?uncached=1", nil, "[7]", 7},
+	}
+
+	for _, test := range tests {
+		w := serveCORS(rt, http.MethodGet, test.path, test.header)
+
+		if b := w.Body.String(); b != test.body {
+			t.Errorf("Invalid body for %s, expected %s, got %s", test.name, test.body, b)
+		}
+
+		if calls != test.calls {
+			t.Errorf("Invalid handler calls for %s, expected %d, got %d", test.name, test.calls, calls)
+		}
+	}
+
+	w := serveCORS(rt, http.MethodGet, "/items?a=1&b=2", nil)
+
+	if w.Header().Get("X-Test") != "a" || w.Header().Get("Age") != "0" {
+		t.Errorf("Invalid cached headers, got %v", w.Header())
+	}
+
+	if h := w.Header().Get("X-Call"); h != "" {
+		t.Errorf("Expected headers added for the request not to be cached, got %s", h)
+	}
+}
+
+func TestCache_Vary(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON, responsetype.TypeCSV).Use(Cache(NewLRUCache(10), "Accept-Language"))
+
+	rt.GET("/a", func(r *Request) interface{} {
+		return Respond(http.StatusOK, []int{1}).Vary("Accept-Language").Cache(time.Minute)
+	})
+
+	expected := []string{"Accept", "Accept-Language"}
+
+	for _, name := range []string{"miss", "hit"} {
+		w := serveCORS(rt, http.MethodGet, "/a", nil)
+
+		if v := w.Header().Values("Vary"); !reflect.DeepEqual(v, expected) {
+			t.Errorf("Invalid vary header on %s, expected %v, got %v", name, expected, v)
+		}
+	}
+}
+
+func TestCache_Private(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply *Reply
+	}{
+		{"private", Respond(http.StatusOK, "a").CacheControl(CacheControl{Private: true, MaxAge: time.Minute})},
+		{"no-store", Respond(http.StatusOK, "a").CacheControl(CacheControl{NoStore: true})},
+		{"cookie", Respond(http.StatusOK, "a").Header("Set-Cookie", "session=a")},
+	}
+
+	for _, test := range tests {
+		store := NewLRUCache(10)
+		reply := test.reply.Cache(time.Minute)
+
+		rt := NewRouter(responsetype.TypeJSON).Use(Cache(store))
+		rt.GET("/a", func(r *Request) interface{} { return reply })
+
+		serveCORS(rt, http.MethodGet, "/a", nil)
+
+		if l := store.Len(); l != 0 {
+			t.Errorf("Expected a %s response not to be stored, got %d", test.name, l)
+		}
+	}
+}
+
+func TestStoredHeader(t *testing.T) {
+	recorded := http.Header{
+		"Vary":                        []string{"Accept", "Origin", "Accept-Language"},
+		"Access-Control-Allow-Origin": []string{"https://a.test"},
+		"X-Test":                      []string{"a", "b"},
+	}
+
+	added := http.Header{
+		"Vary":                        []string{"Accept, Origin"},
+		"Access-Control-Allow-Origin": []string{"https://a.test"},
+		"X-Test":                      []string{"a"},
+	}
+
+	expected := http.Header{
+		"Vary":   []string{"Accept-Language"},
+		"X-Test": []string{"b"},
+	}
+
+	if h := storedHeader(recorded, added); !reflect.DeepEqual(h, expected) {
+		t.Errorf("Invalid stored header, expected %v, got %v", expected, h)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	c := NewLRUCache(2)
+	c.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	resp := func(body string) *CachedResponse {
+		return &CachedResponse{Code: http.StatusOK, Body: []byte(body), Stored: now, Expires: now.Add(time.Minute)}
+	}
+
+	_ = c.Set(ctx, "a", resp("a"))
+	_ = c.Set(ctx, "b", resp("b"))
+
+	if r, _ := c.Get(ctx, "a"); r == nil {
+		t.Errorf("Expected a to be cached")
+	}
+
+	_ = c.Set(ctx, "c", resp("c"))
+
+	if r, _ := c.Get(ctx, "b"); r != nil {
+		t.Errorf("Expected the least recently used entry to be evicted")
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Invalid length, expected 2, got %d", c.Len())
+	}
+
+	now = now.Add(time.Minute)
+
+	if r, _ := c.Get(ctx, "a"); r != nil {
+		t.Errorf("Expected expired entries to be removed")
+	}
+
+	if c.Len() != 1 {
+		t.Errorf("Invalid length, expected 1, got %d", c.Len())
+	}
+}
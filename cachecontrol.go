@@ -0,0 +1,121 @@
+package responsewriter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheControl holds the Cache-Control directives of a response, zero values are omitted.
+// Use NoCache to require revalidation on every use.
+type CacheControl struct {
+	// MaxAge is how long the response is fresh
+	MaxAge time.Duration
+	// SharedMaxAge overrides MaxAge for shared caches, e.g. a CDN
+	SharedMaxAge time.Duration
+	Public       bool
+	Private      bool
+	NoCache      bool
+	NoStore      bool
+	// Immutable promises the response does not change while fresh
+	Immutable bool
+	// StaleWhileRevalidate is how long a stale response may be served while revalidating it
+	StaleWhileRevalidate time.Duration
+}
+
+func (cc CacheControl) String() string {
+	var d []string
+
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{cc.Public, "public"},
+		{cc.Private, "private"},
+		{cc.NoCache, "no-cache"},
+		{cc.NoStore, "no-store"},
+	}
+
+	for _, f := range flags {
+		if f.set {
+			d = append(d, f.name)
+		}
+	}
+
+	ages := []struct {
+		age  time.Duration
+		name string
+	}{
+		{cc.MaxAge, "max-age"},
+		{cc.SharedMaxAge, "s-maxage"},
+		{cc.StaleWhileRevalidate, "stale-while-revalidate"},
+	}
+
+	for _, a := range ages {
+		if a.age > 0 {
+			d = append(d, a.name+"="+strconv.Itoa(int(a.age.Seconds())))
+		}
+	}
+
+	if cc.Immutable {
+		d = append(d, "immutable")
+	}
+
+	return strings.Join(d, ", ")
+}
+
+// CacheControl sets the Cache-Control header of the reply.
+func (r *Reply) CacheControl(cc CacheControl) *Reply {
+	return r.setHeader("Cache-Control", cc.String())
+}
+
+// Expires sets the Expires header of the reply, caches prefer the max-age of CacheControl.
+func (r *Reply) Expires(t time.Time) *Reply {
+	return r.setHeader("Expires", t.UTC().Format(http.TimeFormat))
+}
+
+// Vary adds request headers which select the reply, Accept is added automatically
+// when more than one response type is allowed.
+func (r *Reply) Vary(headers ...string) *Reply {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+
+	addVary(r.header, headers...)
+
+	return r
+}
+
+func (r *Reply) setHeader(key, value string) *Reply {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+
+	r.header.Set(key, value)
+
+	return r
+}
+
+// addVary adds the headers to the Vary header, unless already listed
+func addVary(h http.Header, headers ...string) {
+	for _, header := range headers {
+		if !varies(h, header) {
+			h.Add("Vary", header)
+		}
+	}
+}
+
+func varies(h http.Header, header string) bool {
+	for _, v := range h.Values("Vary") {
+		for _, listed := range strings.Split(v, ",") {
+			listed = strings.TrimSpace(listed)
+
+			if listed == "*" || strings.EqualFold(listed, header) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package responsewriter
+
+import (
+	"net/http"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheControl_String(t *testing.T) {
+	tests := []struct {
+		cc       CacheControl
+		expected string
+	}{
+		{CacheControl{}, ""},
+		{CacheControl{NoStore: true}, "no-store"},
+		{CacheControl{Private: true, MaxAge: time.Minute}, "private, max-age=60"},
+		{
+			CacheControl{Public: true, MaxAge: time.Hour, SharedMaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second, Immutable: true},
+			"public, max-age=3600, s-maxage=60, stale-while-revalidate=30, immutable",
+		},
+	}
+
+	for _, test := range tests {
+		if s := test.cc.String(); s != test.expected {
+			t.Errorf("Invalid cache control, expected %q, got %q", test.expected, s)
+		}
+	}
+}
+
+func TestReply_CacheControl(t *testing.T) {
+	expires := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	r := Status(http.StatusOK).
+		CacheControl(CacheControl{NoCache: true}).
+		CacheControl(CacheControl{Public: true, MaxAge: time.Minute}).
+		Expires(expires).
+		Vary("Accept-Language").
+		Vary("accept-language", "Authorization")
+
+	expected := http.Header{
+		"Cache-Control": []string{"public, max-age=60"},
+		"Expires":       []string{"Tue, 02 Jan 2024 02:04:05 GMT"},
+		"Vary":          []string{"Accept-Language", "Authorization"},
+	}
+
+	if h := r.ToResult().Header; !reflect.DeepEqual(h, expected) {
+		t.Errorf("Invalid reply headers, expected %v, got %v", expected, h)
+	}
+}
+
+func TestVaryAccept(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON, responsetype.TypeCSV).UseCORS(CORS{AllowedOrigins: []string{"https://app.test"}})
+
+	rt.GET("/a", func(r *Request) interface{} { return []string{"a"} })
+	rt.GET("/b", func(r *Request) interface{} {
+		return Respond(http.StatusOK, []string{"b"}).Vary("Accept", "Accept-Language")
+	})
+	rt.Group("/single").WithTypes(responsetype.TypeJSON).GET("/c", func(r *Request) interface{} { return []string{"c"} })
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"/a", []string{"Accept", "Origin"}},
+		{"/b", []string{"Accept", "Origin", "Accept-Language"}},
+		{"/single/c", []string{"Origin"}},
+		{"/unknown", []string{"Accept", "Origin"}},
+	}
+
+	for _, test := range tests {
+		w := serveCORS(rt, http.MethodGet, test.path, map[string]string{"Origin": "https://app.test", "Accept": "text/csv"})
+
+		if v := w.Header().Values("Vary"); !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("Invalid vary header for %s, expected %v, got %v", test.path, test.expected, v)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package responsewriter
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures cross-origin resource sharing.
+type CORS struct {
+	// AllowedOrigins are the allowed origins, "*" allows any origin without credentials
+	// and a single wildcard matches part of an origin, e.g. https://*.example.com
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowedMethods []string
+	// AllowedHeaders are the allowed request headers, the requested headers are allowed when empty
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the client
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request may be cached
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSMiddleware adds CORS headers to every response of an allowed origin and answers preflight
+// requests with 204 No Content. Headers are only added to the responses of the handlers it wraps,
+// prefer Router.UseCORS or Writer.WithCORS to include responses of middleware and unknown routes.
+// It panics when any origin is allowed with credentials, which would allow every site credentialed reads.
+func CORSMiddleware(cors CORS) Middleware {
+	c := cors.normalize()
+
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			if c.apply(r) {
+				return Status(http.StatusNoContent)
+			}
+
+			return next(r)
+		}
+	}
+}
+
+// WithCORS returns a writer adding CORS headers to every response of an allowed origin, including
+// errors of middleware, and answering preflight requests with 204 No Content before calling the
+// handler. Preflight requests are OPTIONS requests, register the handler for OPTIONS as well, or
+// use Router.UseCORS. It panics when any origin is allowed with credentials.
+func (wr *Writer) WithCORS(cors CORS) *Writer {
+	c := *wr
+	c.cors = cors.normalize()
+
+	return &c
+}
+
+// UseCORS adds CORS headers to the routes registered afterwards, before any middleware runs,
+// and answers their preflight requests. Set on the root router, unknown routes get CORS headers
+// as well. Other OPTIONS requests are answered by httprouter's HandleOPTIONS as before.
+func (rt *Router) UseCORS(cors CORS) *Router {
+	c := cors.normalize()
+
+	rt.fallbacks.update(rt, func() {
+		rt.cors = c
+	})
+
+	rt.preflights.install(rt.router)
+
+	return rt
+}
+
+// normalize checks the configuration and applies the defaults
+func (cors CORS) normalize() *CORS {
+	if cors.anyOrigin() && cors.AllowCredentials {
+		panic("Invalid CORS configuration, credentials can not be allowed for any origin")
+	}
+
+	if len(cors.AllowedMethods) == 0 {
+		cors.AllowedMethods = defaultCORSMethods
+	}
+
+	return &cors
+}
+
+// apply adds the CORS headers to the response, returning whether the request is a preflight
+// request, which is answered without calling the handler
+func (cors *CORS) apply(r *Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if len(origin) == 0 {
+		return false
+	}
+
+	h := r.ResponseHeader()
+
+	if !cors.anyOrigin() {
+		addVary(h, "Origin")
+	}
+
+	if !cors.allowsOrigin(origin) {
+		return isPreflight(r)
+	}
+
+	if cors.anyOrigin() {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if cors.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if isPreflight(r) {
+		cors.preflight(r, h)
+
+		return true
+	}
+
+	if len(cors.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
+	}
+
+	return false
+}
+
+// preflights answers the preflight requests of routes registered by a router using CORS,
+// shared by the groups of a router
+type preflights struct {
+	// router holds an OPTIONS handler for every path of a route using CORS
+	router *httprouter.Router
+	// next is the GlobalOPTIONS handler set before, serving other OPTIONS requests
+	next http.Handler
+}
+
+// install sets the preflights as GlobalOPTIONS handler of the router, once
+func (pf *preflights) install(router *httprouter.Router) {
+	if pf.router != nil {
+		return
+	}
+
+	pf.router = httprouter.New()
+	pf.next = router.GlobalOPTIONS
+
+	router.GlobalOPTIONS = pf
+}
+
+// add answers the preflight requests of the path with the writer, the first route of a path wins
+func (pf *preflights) add(path string, wr *Writer, types []ResponseType) {
+	if h, _, _ := pf.router.Lookup(http.MethodOptions, path); h != nil {
+		return
+	}
+
+	pf.router.Handle(http.MethodOptions, path, wr.Handler(func(r *Request) interface{} {
+		return Status(http.StatusNoContent)
+	}, types[0], types[1:]...))
+}
+
+func (pf *preflights) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(r.Header.Get("Origin")) > 0 && len(r.Header.Get("Access-Control-Request-Method")) > 0 {
+		if h, p, _ := pf.router.Lookup(http.MethodOptions, r.URL.Path); h != nil {
+			h(w, r, p)
+
+			return
+		}
+	}
+
+	if pf.next != nil {
+		pf.next.ServeHTTP(w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cors *CORS) preflight(r *Request, h http.Header) {
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+
+	if !containsFold(cors.AllowedMethods, method) {
+		return
+	}
+
+	h.Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+
+	if len(cors.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+	} else if rh := r.Header.Get("Access-Control-Request-Headers"); len(rh) > 0 {
+		h.Set("Access-Control-Allow-Headers", rh)
+	}
+
+	if cors.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+	}
+}
+
+func (cors CORS) anyOrigin() bool {
+	for _, o := range cors.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cors CORS) allowsOrigin(origin string) bool {
+	for _, o := range cors.AllowedOrigins {
+		if matchOrigin(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOrigin matches the origin case insensitively, a single wildcard matches any characters
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+
+	if !wildcard {
+		return pattern == origin
+	}
+
+	return len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+func isPreflight(r *Request) bool {
+	return r.Method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) > 0
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
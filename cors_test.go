@@ -0,0 +1,253 @@
+package responsewriter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+	"time"
+)
+
+func serveCORS(rt *Router, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+
+	rt.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRouter_UseCORS(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON).UseCORS(CORS{
+		AllowedOrigins:   []string{"https://*.example.com", "https://app.test"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+
+	rt.GET("/a", func(r *Request) interface{} { return errors.New("testerror") })
+	rt.POST("/a", func(r *Request) interface{} { return nil })
+
+	w := serveCORS(rt, http.MethodGet, "/a", map[string]string{"Origin": "https://api.example.com"})
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://api.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "X-Request-ID",
+		"Vary":                             "Origin",
+	}
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	for k, v := range expected {
+		if h := w.Header().Get(k); h != v {
+			t.Errorf("Invalid %s header on error, expected %s, got %s", k, v, h)
+		}
+	}
+
+	w = serveCORS(rt, http.MethodGet, "/unknown", map[string]string{"Origin": "https://app.test"})
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Access-Control-Allow-Origin") != "https://app.test" {
+		t.Errorf("Expected CORS headers on not found, got %d %v", w.Code, w.Header())
+	}
+
+	w = serveCORS(rt, http.MethodOptions, "/a", map[string]string{
+		"Origin":                        "https://app.test",
+		"Access-Control-Request-Method": "POST",
+	})
+
+	expected = map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.test",
+		"Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "3600",
+	}
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Invalid preflight status code, expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	for k, v := range expected {
+		if h := w.Header().Get(k); h != v {
+			t.Errorf("Invalid %s header on preflight, expected %s, got %s", k, v, h)
+		}
+	}
+
+	w = serveCORS(rt, http.MethodOptions, "/a", map[string]string{
+		"Origin":                        "https://evil.test",
+		"Access-Control-Request-Method": "POST",
+	})
+
+	if h := w.Header().Get("Access-Control-Allow-Origin"); h != "" {
+		t.Errorf("Expected no CORS headers for a disallowed origin, got %s", h)
+	}
+
+	w = serveCORS(rt, http.MethodOptions, "/a", nil)
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") == "" {
+		t.Errorf("Expected plain OPTIONS to be answered with Allow, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRouter_UseCORSOutermost(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON).
+		Use(RateLimitMiddleware(NewTokenBucket(2, time.Minute), KeyByRoute)).
+		Use(Cache(NewLRUCache(10))).
+		UseCORS(CORS{AllowedOrigins: []string{"https://a.test", "https://b.test"}})
+
+	rt.GET("/a", func(r *Request) interface{} { return Respond(http.StatusOK, "a").Cache(time.Minute) })
+
+	tests := []struct {
+		origin string
+		code   int
+		age    bool
+	}{
+		{"https://a.test", http.StatusOK, false},
+		{"https://b.test", http.StatusOK, true},
+		{"https://a.test", http.StatusTooManyRequests, false},
+	}
+
+	for _, test := range tests {
+		w := serveCORS(rt, http.MethodGet, "/a", map[string]string{"Origin": test.origin})
+
+		if w.Code != test.code || (len(w.Header().Get("Age")) > 0) != test.age {
+			t.Errorf("Invalid response for %s, expected %d, got %d %v", test.origin, test.code, w.Code, w.Header())
+		}
+
+		if h := w.Header().Get("Access-Control-Allow-Origin"); h != test.origin {
+			t.Errorf("Invalid allow origin on %d, expected %s, got %s", w.Code, test.origin, h)
+		}
+	}
+
+	w := serveCORS(rt, http.MethodOptions, "/a", map[string]string{
+		"Origin":                        "https://a.test",
+		"Access-Control-Request-Method": "GET",
+	})
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Expected the preflight request to be answered before middleware, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRouter_GroupUseCORS(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON)
+	rt.HTTPRouter().HandleOPTIONS = false
+
+	rt.GET("/a", func(r *Request) interface{} { return nil })
+
+	api := rt.Group("/api").UseCORS(CORS{AllowedOrigins: []string{"https://app.test"}})
+	api.GET("/b", func(r *Request) interface{} { return nil })
+
+	if rt.HTTPRouter().HandleOPTIONS {
+		t.Error("Expected HandleOPTIONS to be left alone")
+	}
+
+	rt.HTTPRouter().HandleOPTIONS = true
+
+	preflight := map[string]string{"Origin": "https://app.test", "Access-Control-Request-Method": "GET"}
+
+	w := serveCORS(rt, http.MethodOptions, "/api/b", preflight)
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://app.test" {
+		t.Errorf("Expected the preflight request of the group to be answered, got %d %v", w.Code, w.Header())
+	}
+
+	for _, path := range []string{"/a", "/unknown"} {
+		w = serveCORS(rt, http.MethodOptions, path, preflight)
+
+		if h := w.Header().Get("Access-Control-Allow-Origin"); h != "" {
+			t.Errorf("Expected no CORS headers outside the group for %s, got %s", path, h)
+		}
+	}
+}
+
+func TestWriter_WithCORS(t *testing.T) {
+	called := false
+
+	h := NewWriter().WithCORS(CORS{AllowedOrigins: []string{"https://app.test"}}).
+		HTTPHandler(func(r *Request) interface{} {
+			called = true
+
+			return nil
+		}, responsetype.TypeJSON)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://app.test")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+
+	h.ServeHTTP(w, r)
+
+	if called {
+		t.Error("Expected the preflight request to be answered without calling the handler")
+	}
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://app.test" {
+		t.Errorf("Invalid preflight response, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSMiddleware_AnyOrigin(t *testing.T) {
+	fn := CORSMiddleware(CORS{AllowedOrigins: []string{"*"}})(func(r *Request) interface{} { return nil })
+
+	hr := httptest.NewRequest(http.MethodOptions, "/", nil)
+	hr.Header.Set("Origin", "https://any.test")
+	hr.Header.Set("Access-Control-Request-Method", "GET")
+	hr.Header.Set("Access-Control-Request-Headers", "X-Custom")
+
+	r := NewRequest(hr, nil)
+
+	fn(r)
+
+	h := r.ResponseHeader()
+
+	if o := h.Get("Access-Control-Allow-Origin"); o != "*" {
+		t.Errorf("Invalid allow origin, expected *, got %s", o)
+	}
+
+	if ah := h.Get("Access-Control-Allow-Headers"); ah != "X-Custom" {
+		t.Errorf("Invalid allow headers, expected the requested headers, got %s", ah)
+	}
+
+	if v := h.Values("Vary"); len(v) != 2 {
+		t.Errorf("Expected vary on the preflight request headers only, got %v", v)
+	}
+}
+
+func TestCORSMiddleware_AnyOriginCredentials(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("Expected panic for any origin with credentials")
+		}
+	}()
+
+	CORSMiddleware(CORS{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern, origin string
+		expected        bool
+	}{
+		{"https://app.test", "https://APP.test", true},
+		{"https://app.test", "https://app.test.evil", false},
+		{"https://*.example.com", "https://a.example.com", true},
+		{"https://*.example.com", "https://.example.com", false},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://a.example.com.evil", false},
+	}
+
+	for _, test := range tests {
+		if got := matchOrigin(test.pattern, test.origin); got != test.expected {
+			t.Errorf("Invalid match of %s with %s, expected %t, got %t", test.origin, test.pattern, test.expected, got)
+		}
+	}
+}
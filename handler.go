@@ -0,0 +1,24 @@
+package responsewriter
+
+import (
+	"net/http"
+)
+
+// HTTPHandler adapts a handler to an http.Handler, for use with routers other than httprouter.
+//
+// Path parameters are read with Request.Param, the wildcards of http.ServeMux patterns
+// are also available in Request.Params:
+//
+//	mux.Handle("GET /users/{id}", responsewriter.HTTPHandler(getUser, responsetype.TypeJSON))
+func HTTPHandler(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) http.Handler {
+	return ParamHandler(nil, handler, preferredType, allowedTypes...)
+}
+
+// ParamHandler adapts a handler to an http.Handler, reading path parameters with the
+// parameter function of the router, e.g. chi. The parameter names are unknown, so
+// Request.Params stays empty unless the route is an http.ServeMux pattern, use Request.Param:
+//
+//	r.Get("/users/{id}", responsewriter.ParamHandler(chi.URLParam, getUser, responsetype.TypeJSON).ServeHTTP)
+func ParamHandler(params ParamFunc, handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) http.Handler {
+	return defaultWriter.ParamHandler(params, handler, preferredType, allowedTypes...)
+}
@@ -0,0 +1,88 @@
+package responsewriter
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+func TestHTTPHandler_ServeMux(t *testing.T) {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /users/{id}", HTTPHandler(func(r *Request) interface{} {
+		return []string{r.Param("id")}
+	}, responsetype.TypeJSON, responsetype.TypeCSV))
+
+	mux.Handle("GET /teams/{team}/files/{path...}", HTTPHandler(func(r *Request) interface{} {
+		return r.Params.ByName("team") + ":" + r.Params.ByName("path")
+	}, responsetype.TypeJSON))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/12", nil)
+	r.Header.Set("Accept", "text/csv")
+
+	mux.ServeHTTP(w, r)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Invalid content type, expected %s, got %s", "text/csv", ct)
+	}
+
+	if b := w.Body.String(); b != "12\n" {
+		t.Errorf("Invalid body, expected %s, got %s", "12\n", b)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/teams/a/files/b/c.txt", nil))
+
+	if b := w.Body.String(); b != "a:b/c.txt" {
+		t.Errorf("Invalid params body, expected %s, got %s", "a:b/c.txt", b)
+	}
+}
+
+func TestParamHandler(t *testing.T) {
+	params := func(r *http.Request, name string) string {
+		if name == "id" {
+			return "34"
+		}
+
+		return ""
+	}
+
+	h := ParamHandler(params, func(r *Request) interface{} {
+		if len(r.Params) > 0 {
+			return r.Params
+		}
+
+		return r.Param("id") + r.Param("other")
+	}, responsetype.TypeJSON)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if b := w.Body.String(); b != "34" {
+		t.Errorf("Invalid body, expected %s, got %s", "34", b)
+	}
+}
+
+func TestRequest_Param(t *testing.T) {
+	hr := httptest.NewRequest(http.MethodGet, "/", nil)
+	hr.SetPathValue("id", "mux")
+
+	r := NewRequest(hr, httprouter.Params{{Key: "id", Value: "router"}})
+
+	if p := r.Param("id"); p != "router" {
+		t.Errorf("Invalid param, expected %s, got %s", "router", p)
+	}
+
+	r = NewRequest(hr, nil)
+
+	if p := r.Param("id"); p != "mux" {
+		t.Errorf("Invalid param, expected %s, got %s", "mux", p)
+	}
+
+	if p := r.Param("missing"); p != "" {
+		t.Errorf("Invalid param, expected empty, got %s", p)
+	}
+}
@@ -0,0 +1,297 @@
+package responsewriter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader identifies retries of the same POST or PATCH request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBodySize limits the body read to fingerprint a request
+const maxIdempotentBodySize = 1 << 20
+
+// IdempotencyRecord is the stored state of an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request the key was first used with
+	Fingerprint string
+	// Done is set once the response is stored, the request is in progress until then
+	Done   bool
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore stores the responses of idempotency keys, implementations backed by
+// a shared store allow replaying across instances.
+type IdempotencyStore interface {
+	// Begin returns the record of the key, or stores an in progress record with the
+	// fingerprint and returns nil when the key is new
+	Begin(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, error)
+	// Complete stores the response of the key
+	Complete(ctx context.Context, key string, record *IdempotencyRecord) error
+	// Release forgets the key, allowing the request to be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency replays the stored response of POST and PATCH requests with a known Idempotency-Key
+// header. A request using a key which is still in progress is answered with 409 Conflict, a key used
+// with another method, URL, principal or body with 422 Unprocessable Entity. Without principal the
+// Authorization header is compared instead. Bodies over 1 MiB are answered with 413 Request Entity Too Large.
+//
+// Add the middleware after authentication and rate limiting, keys are scoped to the principal.
+// Only final outcomes are stored, after an error like 401, 429 or 5xx the request can be retried.
+func Idempotency(store IdempotencyStore) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+				return next(r)
+			}
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+
+			if len(key) == 0 {
+				return next(r)
+			}
+
+			if r.principal != nil {
+				key = r.principal.Subject + "\n" + key
+			}
+
+			fingerprint, err := requestFingerprint(r)
+
+			if errors.Is(err, errBodyTooLarge) {
+				return errorReply(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge), err)
+			} else if err != nil {
+				return errorReply(http.StatusBadRequest, "Invalid request body", err)
+			}
+
+			rec, err := store.Begin(r.Context(), key, fingerprint)
+
+			switch {
+			case err != nil:
+				r.logger().WithError(err).Error("Failed to read idempotency key, serving request")
+
+				return next(r)
+			case rec == nil:
+			case rec.Fingerprint != fingerprint:
+				return errorReply(http.StatusUnprocessableEntity, "Idempotency key reused with a different request", nil)
+			case !rec.Done:
+				return errorReply(http.StatusConflict, "A request with this idempotency key is in progress", nil)
+			default:
+				return &idempotentReplay{record: rec}
+			}
+
+			ir := &idempotentRequest{store: store, key: key, fingerprint: fingerprint}
+
+			defer func() {
+				if p := recover(); p != nil {
+					_ = store.Release(r.Context(), key)
+
+					panic(p)
+				}
+			}()
+
+			resp := next(r)
+
+			r.idempotency = ir
+
+			return resp
+		}
+	}
+}
+
+// idempotentReplay is returned by the middleware to serve the stored response of a completed request
+type idempotentReplay struct {
+	record *IdempotencyRecord
+}
+
+// write writes the stored response, headers added by middleware for this request take precedence
+func (ir *idempotentReplay) write(w http.ResponseWriter, r *Request) {
+	writeStoredHeader(w, ir.record.Header, r.header)
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(ir.record.Code)
+
+	_, _ = w.Write(ir.record.Body)
+}
+
+// idempotentRequest stores the response of a POST or PATCH request with a new idempotency key
+type idempotentRequest struct {
+	store       IdempotencyStore
+	key         string
+	fingerprint string
+
+	recorder *responseRecorder
+}
+
+// record returns a writer recording the response for the store
+func (ir *idempotentRequest) record(w http.ResponseWriter) http.ResponseWriter {
+	ir.recorder = &responseRecorder{ResponseWriter: w}
+
+	return ir.recorder
+}
+
+// complete stores final responses, without the headers added by middleware for this request,
+// and releases the key after responses worth retrying
+func (ir *idempotentRequest) complete(r *Request) {
+	rec := ir.recorder
+
+	var err error
+
+	if retryable(rec.code) {
+		err = ir.store.Release(r.Context(), ir.key)
+	} else {
+		err = ir.store.Complete(r.Context(), ir.key, &IdempotencyRecord{
+			Fingerprint: ir.fingerprint,
+			Done:        true,
+			Code:        rec.code,
+			Header:      storedHeader(rec.header, r.header),
+			Body:        rec.body.Bytes(),
+		})
+	}
+
+	if err != nil {
+		r.logger().WithError(err).Error("Failed to store idempotency key")
+	}
+}
+
+// retryable reports whether a response depends on transient state, e.g. credentials or load
+func retryable(code int) bool {
+	switch code {
+	case 0, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout,
+		http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+
+	return code >= http.StatusInternalServerError
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// requestFingerprint hashes the method, URL, principal or credentials and body,
+// the body is restored for the handler
+func requestFingerprint(r *Request) (string, error) {
+	h := sha256.New()
+
+	identity := "authorization:" + r.Header.Get("Authorization")
+
+	if r.principal != nil {
+		identity = "principal:" + r.principal.Subject
+	}
+
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + identity + "\n"))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+
+		if err != nil {
+			return "", err
+		}
+
+		if len(b) > maxIdempotentBodySize {
+			return "", errBodyTooLarge
+		}
+
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(b))
+
+		h.Write(b)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MemoryIdempotencyStore is an in memory IdempotencyStore, forgetting keys after the TTL.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyRecord
+	pruned  time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore keeps keys for the TTL, which also limits how long a request may be in progress
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	if ttl <= 0 {
+		panic("Invalid idempotency store TTL")
+	}
+
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		records: make(map[string]*memoryIdempotencyRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Begin(_ context.Context, key, fingerprint string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	s.prune(now)
+
+	if rec, ok := s.records[key]; ok && !now.After(rec.expires) {
+		c := rec.IdempotencyRecord
+
+		return &c, nil
+	}
+
+	s.records[key] = &memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint},
+		expires:           now.Add(s.ttl),
+	}
+
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = &memoryIdempotencyRecord{
+		IdempotencyRecord: *record,
+		expires:           s.now().Add(s.ttl),
+	}
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+// prune removes expired records, at most once per TTL
+func (s *MemoryIdempotencyStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < s.ttl {
+		return
+	}
+
+	s.pruned = now
+
+	for k, rec := range s.records {
+		if now.After(rec.expires) {
+			delete(s.records, k)
+		}
+	}
+}
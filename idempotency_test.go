@@ -0,0 +1,283 @@
+package responsewriter
+
+import (
+	"context"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveIdempotent(fn httprouter.Handle, method, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "/payments", strings.NewReader(body))
+
+	if len(key) > 0 {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	fn(w, r, httprouter.Params{})
+
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+
+	store := NewMemoryIdempotencyStore(time.Hour)
+
+	fn := ResponseHandler(Idempotency(store)(func(r *Request) interface{} {
+		calls++
+
+		b, _ := io.ReadAll(r.Body)
+
+		if string(b) == "fail" {
+			return errors.New("testerror")
+		}
+
+		return Respond(http.StatusCreated, map[string]interface{}{"call": calls, "body": string(b)}).Header("Location", "/payments/1")
+	}), responsetype.TypeJSON)
+
+	first := serveIdempotent(fn, http.MethodPost, "a", "pay")
+	second := serveIdempotent(fn, http.MethodPost, "a", "pay")
+
+	if calls != 1 {
+		t.Errorf("Expected the handler to be called once, got %d", calls)
+	}
+
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() || second.Body.String() != `{"body":"pay","call":1}` {
+		t.Errorf("Invalid replay, expected %d %s, got %d %s", first.Code, first.Body.String(), second.Code, second.Body.String())
+	}
+
+	if second.Header().Get("Location") != "/payments/1" || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Invalid replay headers, got %v", second.Header())
+	}
+
+	w := serveIdempotent(fn, http.MethodPost, "a", "other")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Invalid status code for a reused key, expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	serveIdempotent(fn, http.MethodPost, "b", "fail")
+	serveIdempotent(fn, http.MethodPost, "b", "fail")
+
+	if calls != 3 {
+		t.Errorf("Expected server errors not to be stored, got %d calls", calls)
+	}
+
+	serveIdempotent(fn, http.MethodPut, "a", "pay")
+	serveIdempotent(fn, http.MethodPost, "", "pay")
+
+	if calls != 5 {
+		t.Errorf("Expected requests without key or with other methods to be served, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_Conflict(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Hour)
+
+	if rec, _ := store.Begin(context.Background(), "a", "other"); rec != nil {
+		t.Fatalf("Expected a new key, got %#v", rec)
+	}
+
+	fn := ResponseHandler(Idempotency(store)(func(r *Request) interface{} { return nil }), responsetype.TypeJSON)
+
+	w := serveIdempotent(fn, http.MethodPatch, "a", "")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	fp, _ := requestFingerprint(NewRequest(httptest.NewRequest(http.MethodPatch, "/payments", strings.NewReader("")), nil))
+
+	_ = store.Release(context.Background(), "a")
+	_, _ = store.Begin(context.Background(), "a", fp)
+
+	w = serveIdempotent(fn, http.MethodPatch, "a", "")
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	if b := w.Body.String(); b != `{"code":409,"description":"A request with this idempotency key is in progress"}` {
+		t.Errorf("Invalid body, got %s", b)
+	}
+}
+
+func TestIdempotency_Authenticated(t *testing.T) {
+	calls := 0
+
+	auth := BearerAuth("api", func(_ context.Context, token string) (*Principal, error) {
+		return &Principal{Subject: token}, nil
+	})
+
+	rt := NewRouter(responsetype.TypeJSON)
+	rt.Use(Authenticate(auth), Idempotency(NewMemoryIdempotencyStore(time.Hour)))
+	rt.POST("/payments", func(r *Request) interface{} {
+		calls++
+
+		return Respond(http.StatusCreated, r.Principal().Subject)
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		header := map[string]string{IdempotencyKeyHeader: "a"}
+
+		if len(token) > 0 {
+			header["Authorization"] = "Bearer " + token
+		}
+
+		return serveCORS(rt, http.MethodPost, "/payments", header)
+	}
+
+	serve("alice")
+
+	if w := serve(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Invalid status code without credentials, expected %d, got %d %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+
+	if w := serve("bob"); w.Code != http.StatusCreated || w.Body.String() != "bob" {
+		t.Errorf("Expected the key to be scoped to the principal, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := serve("alice"); w.Body.String() != "alice" || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected a replay, got %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the handler to be called twice, got %d", calls)
+	}
+}
+
+func TestIdempotency_TokenRefresh(t *testing.T) {
+	calls := 0
+
+	auth := BearerAuth("api", func(_ context.Context, token string) (*Principal, error) {
+		subject, _, _ := strings.Cut(token, ".")
+
+		return &Principal{Subject: subject}, nil
+	})
+
+	rt := NewRouter(responsetype.TypeJSON)
+	rt.Use(Authenticate(auth), Idempotency(NewMemoryIdempotencyStore(time.Hour)))
+	rt.POST("/payments", func(r *Request) interface{} {
+		calls++
+
+		return Status(http.StatusCreated)
+	})
+
+	for _, token := range []string{"alice.1", "alice.2"} {
+		w := serveCORS(rt, http.MethodPost, "/payments", map[string]string{
+			IdempotencyKeyHeader: "a",
+			"Authorization":      "Bearer " + token,
+		})
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Invalid status code with token %s, expected %d, got %d", token, http.StatusCreated, w.Code)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected a replay after refreshing the token, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	fn := ResponseHandler(Idempotency(NewMemoryIdempotencyStore(time.Hour))(func(r *Request) interface{} {
+		return Status(http.StatusCreated)
+	}), responsetype.TypeJSON)
+
+	if w := serveIdempotent(fn, http.MethodPost, "a", strings.Repeat("a", maxIdempotentBodySize+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	if w := serveIdempotent(fn, http.MethodPost, "b", strings.Repeat("a", maxIdempotentBodySize)); w.Code != http.StatusCreated {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusCreated, w.Code)
+	}
+}
+
+func TestIdempotency_Retryable(t *testing.T) {
+	codes := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusConflict,
+		http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+	for _, code := range codes {
+		calls := 0
+
+		fn := ResponseHandler(Idempotency(NewMemoryIdempotencyStore(time.Hour))(func(r *Request) interface{} {
+			calls++
+
+			if calls == 1 {
+				return Status(code).Header("Retry-After", "3600")
+			}
+
+			return Status(http.StatusCreated)
+		}), responsetype.TypeJSON)
+
+		serveIdempotent(fn, http.MethodPost, "a", "pay")
+
+		if w := serveIdempotent(fn, http.MethodPost, "a", "pay"); w.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("Expected a retry after %d, got %d after %d calls", code, w.Code, calls)
+		}
+	}
+
+	calls := 0
+
+	fn := ResponseHandler(Idempotency(NewMemoryIdempotencyStore(time.Hour))(func(r *Request) interface{} {
+		calls++
+
+		return Status(http.StatusNotFound)
+	}), responsetype.TypeJSON)
+
+	serveIdempotent(fn, http.MethodPost, "a", "pay")
+	serveIdempotent(fn, http.MethodPost, "a", "pay")
+
+	if calls != 1 {
+		t.Errorf("Expected final client errors to be stored, got %d calls", calls)
+	}
+}
+
+func TestMemoryIdempotencyStore_TTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	_, _ = store.Begin(ctx, "a", "fp")
+
+	if rec, _ := store.Begin(ctx, "a", "fp"); rec == nil || rec.Done {
+		t.Errorf("Expected an in progress record, got %#v", rec)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if rec, _ := store.Begin(ctx, "a", "fp"); rec != nil {
+		t.Errorf("Expected the key to expire, got %#v", rec)
+	}
+
+	_, _ = store.Begin(ctx, "b", "fp")
+
+	now = now.Add(2 * time.Minute)
+
+	_, _ = store.Begin(ctx, "c", "fp")
+
+	if l := len(store.records); l != 1 {
+		t.Errorf("Expected expired keys to be pruned, got %d records", l)
+	}
+}
+
+func TestNewMemoryIdempotencyStore(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("Expected panic for a TTL of zero")
+		}
+	}()
+
+	NewMemoryIdempotencyStore(0)
+}
@@ -0,0 +1,238 @@
+package responsewriter
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics observes every response served by a Writer.
+type Metrics interface {
+	Observe(o Observation)
+}
+
+// Observation describes a served response.
+type Observation struct {
+	// Route is the route of the writer, empty when unknown
+	Route  string
+	Method string
+	Code   int
+	// Type is the accepted type of the negotiated response type
+	Type     string
+	Duration time.Duration
+	// Size is the number of body bytes written
+	Size int
+	// Fallback is set when the return value was not recognized and the default error was served
+	Fallback bool
+}
+
+// StatusClass returns the class of the status code, e.g. 2xx
+func (o Observation) StatusClass() string {
+	return strconv.Itoa(o.Code/100) + "xx"
+}
+
+// PrometheusMetrics collects observations in memory and serves them in the Prometheus text format.
+//
+// It collects:
+//   - <namespace>_responses_total{route,method,class,type}
+//   - <namespace>_fallbacks_total{route,type}
+//   - <namespace>_response_duration_seconds{route,method} histogram
+//   - <namespace>_response_size_bytes{route,method} histogram
+type PrometheusMetrics struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu        sync.Mutex
+	responses map[labels]uint64
+	fallbacks map[labels]uint64
+	durations map[labels]*histogram
+	sizes     map[labels]*histogram
+}
+
+var (
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets     = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// labels is a list of label name and value pairs, rendered in order
+type labels string
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	return &PrometheusMetrics{
+		namespace:       namespace,
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		responses:       make(map[labels]uint64),
+		fallbacks:       make(map[labels]uint64),
+		durations:       make(map[labels]*histogram),
+		sizes:           make(map[labels]*histogram),
+	}
+}
+
+// WithDurationBuckets sets the upper bounds in seconds of the duration histogram buckets
+func (pm *PrometheusMetrics) WithDurationBuckets(buckets ...float64) *PrometheusMetrics {
+	pm.durationBuckets = sortedBuckets(buckets)
+
+	return pm
+}
+
+// WithSizeBuckets sets the upper bounds in bytes of the size histogram buckets
+func (pm *PrometheusMetrics) WithSizeBuckets(buckets ...float64) *PrometheusMetrics {
+	pm.sizeBuckets = sortedBuckets(buckets)
+
+	return pm
+}
+
+func (pm *PrometheusMetrics) Observe(o Observation) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	o.Method = methodLabel(o.Method)
+
+	pm.responses[newLabels("route", o.Route, "method", o.Method, "class", o.StatusClass(), "type", o.Type)]++
+
+	if o.Fallback {
+		pm.fallbacks[newLabels("route", o.Route, "type", o.Type)]++
+	}
+
+	l := newLabels("route", o.Route, "method", o.Method)
+
+	observe(pm.durations, l, pm.durationBuckets, o.Duration.Seconds())
+	observe(pm.sizes, l, pm.sizeBuckets, float64(o.Size))
+}
+
+// methodLabel returns the method, or OTHER for unknown methods, which would allow clients
+// to create any number of series
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return "OTHER"
+}
+
+// ServeHTTP serves the collected metrics in the Prometheus text exposition format
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write([]byte(pm.String()))
+}
+
+// String returns the collected metrics in the Prometheus text exposition format
+func (pm *PrometheusMetrics) String() string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	sb := &strings.Builder{}
+
+	writeCounter(sb, pm.name("responses_total"), "Number of responses.", pm.responses)
+	writeCounter(sb, pm.name("fallbacks_total"), "Number of unrecognized return values served as default error.", pm.fallbacks)
+	writeHistogram(sb, pm.name("response_duration_seconds"), "Duration of handling and writing the response.", pm.durationBuckets, pm.durations)
+	writeHistogram(sb, pm.name("response_size_bytes"), "Size of the response body.", pm.sizeBuckets, pm.sizes)
+
+	return sb.String()
+}
+
+func (pm *PrometheusMetrics) name(name string) string {
+	if len(pm.namespace) == 0 {
+		return name
+	}
+
+	return pm.namespace + "_" + name
+}
+
+func observe(hs map[labels]*histogram, l labels, buckets []float64, v float64) {
+	h, ok := hs[l]
+
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		hs[l] = h
+	}
+
+	for i, b := range buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += v
+	h.count++
+}
+
+func writeCounter(sb *strings.Builder, name, help string, values map[labels]uint64) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	for _, l := range sortedLabels(values) {
+		fmt.Fprintf(sb, "%s{%s} %d\n", name, l, values[l])
+	}
+}
+
+func writeHistogram(sb *strings.Builder, name, help string, buckets []float64, values map[labels]*histogram) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	for _, l := range sortedLabels(values) {
+		h := values[l]
+
+		for i, b := range buckets {
+			fmt.Fprintf(sb, "%s_bucket{%s,le=\"%s\"} %d\n", name, l, formatFloat(b), h.counts[i])
+		}
+
+		fmt.Fprintf(sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, h.count)
+		fmt.Fprintf(sb, "%s_sum{%s} %s\n", name, l, formatFloat(h.sum))
+		fmt.Fprintf(sb, "%s_count{%s} %d\n", name, l, h.count)
+	}
+}
+
+func newLabels(pairs ...string) labels {
+	parts := make([]string, 0, len(pairs)/2)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"=\""+escapeLabel(pairs[i+1])+"\"")
+	}
+
+	return labels(strings.Join(parts, ","))
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func sortedLabels[V any](values map[labels]V) []labels {
+	ls := make([]labels, 0, len(values))
+
+	for l := range values {
+		ls = append(ls, l)
+	}
+
+	sort.Slice(ls, func(i, j int) bool { return ls[i] < ls[j] })
+
+	return ls
+}
+
+func sortedBuckets(buckets []float64) []float64 {
+	b := append([]float64(nil), buckets...)
+
+	sort.Float64s(b)
+
+	return b
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,134 @@
+package responsewriter
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"strings"
+	"testing"
+	"time"
+)
+
+type metricsMock struct {
+	observations []Observation
+}
+
+func (m *metricsMock) Observe(o Observation) {
+	m.observations = append(m.observations, o)
+}
+
+func TestWriter_Metrics(t *testing.T) {
+	m := &metricsMock{}
+
+	wr := NewWriter().WithMetrics(m).WithRoute("/users/:id")
+
+	fn := wr.Handler(func(r *Request) interface{} {
+		if r.Method == http.MethodDelete {
+			return make(chan int)
+		}
+
+		return "abc"
+	}, responsetype.TypeJSON, responsetype.TypeCSV)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Accept", "text/csv")
+
+	fn(w, r, httprouter.Params{})
+
+	fn(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil), httprouter.Params{})
+
+	if len(m.observations) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(m.observations))
+	}
+
+	o := m.observations[0]
+
+	if o.Route != "/users/:id" || o.Method != http.MethodGet || o.Code != http.StatusOK || o.Type != "text/csv" || o.Size != 3 || o.Fallback {
+		t.Errorf("Invalid observation, got %#v", o)
+	}
+
+	o = m.observations[1]
+
+	if o.Code != http.StatusInternalServerError || o.Type != "application/json" || !o.Fallback || o.StatusClass() != "5xx" {
+		t.Errorf("Invalid fallback observation, got %#v", o)
+	}
+
+	if defaultWriter.metrics != nil || len(defaultWriter.route) > 0 {
+		t.Errorf("Expected the default writer to be unchanged")
+	}
+}
+
+func TestRouter_WithWriter(t *testing.T) {
+	m := &metricsMock{}
+
+	root := NewRouter(responsetype.TypeJSON)
+	rt := root.WithWriter(NewWriter().WithMetrics(m))
+
+	if root.writer == rt.writer {
+		t.Errorf("Expected the root router to be unchanged")
+	}
+
+	rt.GET("/users/:id", func(r *Request) interface{} { return nil })
+
+	serveRouter(rt, http.MethodGet, "/users/1", "")
+	serveRouter(rt, http.MethodGet, "/unknown", "")
+
+	if len(m.observations) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(m.observations))
+	}
+
+	if o := m.observations[0]; o.Route != "/users/:id" || o.Code != http.StatusNoContent {
+		t.Errorf("Invalid observation, got %#v", o)
+	}
+
+	if o := m.observations[1]; o.Route != "" || o.Code != http.StatusNotFound {
+		t.Errorf("Invalid not found observation, got %#v", o)
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	pm := NewPrometheusMetrics("api").WithDurationBuckets(1, 0.1).WithSizeBuckets(10)
+
+	pm.Observe(Observation{Route: "/a", Method: http.MethodGet, Code: 200, Type: "application/json", Duration: 50 * time.Millisecond, Size: 5})
+	pm.Observe(Observation{Route: "/a", Method: http.MethodGet, Code: 500, Type: "application/json", Duration: 500 * time.Millisecond, Size: 20, Fallback: true})
+	pm.Observe(Observation{Route: "/\"b\"", Method: http.MethodPost, Code: 201, Type: "text/csv"})
+	pm.Observe(Observation{Route: "/a", Method: "FOO", Code: 404, Type: "application/json"})
+
+	w := httptest.NewRecorder()
+	pm.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Invalid content type, got %s", ct)
+	}
+
+	expected := []string{
+		"# TYPE api_responses_total counter",
+		`api_responses_total{route="/\"b\"",method="POST",class="2xx",type="text/csv"} 1`,
+		`api_responses_total{route="/a",method="GET",class="2xx",type="application/json"} 1`,
+		`api_responses_total{route="/a",method="GET",class="5xx",type="application/json"} 1`,
+		`api_responses_total{route="/a",method="OTHER",class="4xx",type="application/json"} 1`,
+		`api_fallbacks_total{route="/a",type="application/json"} 1`,
+		"# TYPE api_response_duration_seconds histogram",
+		`api_response_duration_seconds_bucket{route="/a",method="GET",le="0.1"} 1`,
+		`api_response_duration_seconds_bucket{route="/a",method="GET",le="1"} 2`,
+		`api_response_duration_seconds_bucket{route="/a",method="GET",le="+Inf"} 2`,
+		`api_response_duration_seconds_sum{route="/a",method="GET"} 0.55`,
+		`api_response_duration_seconds_count{route="/a",method="GET"} 2`,
+		`api_response_size_bytes_bucket{route="/a",method="GET",le="10"} 1`,
+		`api_response_size_bytes_sum{route="/a",method="GET"} 25`,
+	}
+
+	b := w.Body.String()
+
+	for _, line := range expected {
+		if !strings.Contains(b, line+"\n") {
+			t.Errorf("Expected metrics to contain %s, got %s", line, b)
+		}
+	}
+
+	if strings.Contains(b, "FOO") {
+		t.Errorf("Expected unknown methods to be labelled OTHER, got %s", b)
+	}
+}
@@ -0,0 +1,468 @@
+package responsewriter
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"path"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the registry documents.
+const OpenAPIVersion = "3.1.0"
+
+// Route describes a handler together with the types it accepts and returns.
+type Route struct {
+	Method  string
+	Path    string
+	Summary string
+	Tags    []string
+
+	Handler Handler
+	// Types are the allowed response types, the first one is preferred
+	Types []ResponseType
+	// Middleware wraps the handler of the route only, e.g. RequireScopes
+	Middleware []Middleware
+
+	// Params declares path and query parameters, path parameters
+	// which are not declared are documented as strings
+	Params []Param
+	// Request is a value of the type of the request body, nil for no body
+	Request interface{}
+	// Response is a value of the type of the response body, nil for no content
+	Response interface{}
+	// Code is the success status code, 200 by default or 204 without Response
+	Code int
+	// Errors are the documented error status codes, described by the JSONError schema
+	Errors []int
+}
+
+// Param describes a path or query parameter.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	// Type is a value of the parameter type, string when nil
+	Type interface{}
+}
+
+// Registry records routes and generates an OpenAPI document describing them.
+type Registry struct {
+	Title   string
+	Version string
+
+	mu     sync.RWMutex
+	routes []Route
+}
+
+// OpenAPI document, only the parts generated by the registry are modelled
+type (
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components OpenAPIComponents                       `json:"components"`
+	}
+
+	OpenAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	}
+
+	OpenAPIOperation struct {
+		Summary     string                      `json:"summary,omitempty"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+	}
+
+	OpenAPIParameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"`
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                         `json:"required,omitempty"`
+		Content  map[string]*OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	}
+)
+
+func NewRegistry(title, version string) *Registry {
+	return &Registry{
+		Title:   title,
+		Version: version,
+	}
+}
+
+// Add records the route and returns its handle
+func (reg *Registry) Add(route Route) httprouter.Handle {
+	if len(route.Types) == 0 {
+		panic("No response type given for route " + route.Method + " " + route.Path)
+	}
+
+	reg.record(route)
+
+	return ResponseHandler(wrap(route.Handler, route.Middleware), route.Types[0], route.Types[1:]...)
+}
+
+func (reg *Registry) record(route Route) {
+	reg.mu.Lock()
+	reg.routes = append(reg.routes, route)
+	reg.mu.Unlock()
+}
+
+// Handle records the route and registers it on the router
+func (reg *Registry) Handle(router *httprouter.Router, route Route) {
+	router.Handle(route.Method, route.Path, reg.Add(route))
+}
+
+// Serve registers the OpenAPI document on the router at the given path
+func (reg *Registry) Serve(router *httprouter.Router, path string) {
+	router.GET(path, reg.Handler())
+}
+
+// Handler serves the OpenAPI document as JSON
+func (reg *Registry) Handler() httprouter.Handle {
+	return func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		b, err := json.Marshal(reg.Document())
+
+		if err != nil {
+			log.WithError(err).Error("Failed to marshal OpenAPI document")
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(b)
+	}
+}
+
+// Document generates the OpenAPI document of all recorded routes
+func (reg *Registry) Document() *OpenAPIDocument {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	g := &schemaGenerator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    OpenAPIInfo{Title: reg.Title, Version: reg.Version},
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+
+	errorSchema := g.schema(reflect.TypeOf(responsetype.JSONError{}))
+
+	for _, route := range reg.routes {
+		path, pathParams := openAPIPath(route.Path)
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+
+		doc.Paths[path][strings.ToLower(route.Method)] = g.operation(route, pathParams, errorSchema)
+	}
+
+	doc.Components.Schemas = g.schemas
+
+	return doc
+}
+
+func (g *schemaGenerator) operation(route Route, pathParams []string, errorSchema *Schema) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		Summary:   route.Summary,
+		Tags:      route.Tags,
+		Responses: make(map[string]*OpenAPIResponse),
+	}
+
+	declared := make(map[string]bool)
+
+	for _, p := range route.Params {
+		in := p.In
+
+		if len(in) == 0 {
+			in = "query"
+		}
+
+		declared[in+":"+p.Name] = true
+
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:        p.Name,
+			In:          in,
+			Description: p.Description,
+			Required:    p.Required || in == "path",
+			Schema:      g.paramSchema(p.Type),
+		})
+	}
+
+	for _, name := range pathParams {
+		if declared["path:"+name] {
+			continue
+		}
+
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMediaType{
+				"application/json": {Schema: g.schema(reflect.TypeOf(route.Request))},
+			},
+		}
+	}
+
+	code := route.Code
+
+	if route.Response == nil {
+		if code == 0 {
+			code = http.StatusNoContent
+		}
+
+		op.Responses[strconv.Itoa(code)] = &OpenAPIResponse{Description: http.StatusText(code)}
+	} else {
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		op.Responses[strconv.Itoa(code)] = &OpenAPIResponse{
+			Description: http.StatusText(code),
+			Content:     mediaTypes(route.Types, g.schema(reflect.TypeOf(route.Response))),
+		}
+	}
+
+	for _, ec := range route.Errors {
+		op.Responses[strconv.Itoa(ec)] = &OpenAPIResponse{
+			Description: http.StatusText(ec),
+			Content:     mediaTypes(route.Types, errorSchema),
+		}
+	}
+
+	op.Responses["default"] = &OpenAPIResponse{
+		Description: "Error",
+		Content:     mediaTypes(route.Types, errorSchema),
+	}
+
+	return op
+}
+
+func mediaTypes(types []ResponseType, schema *Schema) map[string]*OpenAPIMediaType {
+	content := make(map[string]*OpenAPIMediaType)
+
+	for _, t := range types {
+		content[t.GetAcceptedType()] = &OpenAPIMediaType{Schema: schema}
+	}
+
+	return content
+}
+
+// openAPIPath converts an httprouter path to an OpenAPI path and returns its parameter names
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+
+	var params []string
+
+	for i, s := range segments {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	// names are the component names of the named structs
+	names map[reflect.Type]string
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	importPathRegexp    = regexp.MustCompile(`[\w.\-]+/`)
+	invalidSchemaRegexp = regexp.MustCompile(`[^a-zA-Z0-9._\-]+`)
+)
+
+func (g *schemaGenerator) paramSchema(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{Type: "string"}
+	}
+
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema generates the JSON schema of the type, named structs are added to the components
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return g.structSchema(t)
+		}
+
+		name, ok := g.names[t]
+
+		if !ok {
+			name = g.componentName(t)
+
+			// Reserve the name before generating to support recursive types
+			g.names[t] = name
+			g.schemas[name] = nil
+			g.schemas[name] = g.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+// componentName returns an unused, valid component name of the type. Generic instantiations
+// are named after their type arguments without import path, e.g. Page_user.User, types of
+// other packages sharing a name are qualified with the package name, e.g. admin.User.
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := importPathRegexp.ReplaceAllString(t.Name(), "")
+	name = strings.Trim(invalidSchemaRegexp.ReplaceAllString(name, "_"), "_")
+
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+
+	name = path.Base(t.PkgPath()) + "." + name
+
+	for i, base := 2, name; ; i++ {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	g.addFields(s, t)
+
+	sort.Strings(s.Required)
+
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && len(name) == 0 {
+			ft := f.Type
+
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+
+				continue
+			}
+		}
+
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		s.Properties[name] = g.schema(f.Type)
+
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
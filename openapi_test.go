@@ -0,0 +1,208 @@
+package responsewriter
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type openAPIUser struct {
+	ID       int64          `json:"id"`
+	Name     string         `json:"name"`
+	Email    string         `json:"email,omitempty"`
+	Created  time.Time      `json:"created"`
+	Tags     []string       `json:"tags"`
+	Parent   *openAPIUser   `json:"parent"`
+	Meta     map[string]int `json:"meta,omitempty"`
+	Internal string         `json:"-"`
+	private  string
+}
+
+func TestRegistry_Document(t *testing.T) {
+	reg := NewRegistry("Test", "1.0.0")
+
+	router := httprouter.New()
+
+	reg.Handle(router, Route{
+		Method:   http.MethodPost,
+		Path:     "/users",
+		Summary:  "Create user",
+		Handler:  func(r *Request) interface{} { return nil },
+		Types:    []ResponseType{responsetype.TypeJSON, responsetype.TypeMsgPack},
+		Request:  openAPIUser{},
+		Response: &openAPIUser{},
+		Code:     http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest},
+	})
+
+	reg.Handle(router, Route{
+		Method:  http.MethodDelete,
+		Path:    "/users/:id/*rest",
+		Handler: func(r *Request) interface{} { return nil },
+		Types:   []ResponseType{responsetype.TypeJSON},
+		Params:  []Param{{Name: "id", In: "path", Type: 0}, {Name: "force", Type: true}},
+	})
+
+	doc := reg.Document()
+
+	if doc.OpenAPI != OpenAPIVersion || doc.Info.Title != "Test" || doc.Info.Version != "1.0.0" {
+		t.Errorf("Invalid document header, got %#v %#v", doc.OpenAPI, doc.Info)
+	}
+
+	post := doc.Paths["/users"]["post"]
+
+	if post == nil {
+		t.Fatalf("Expected post operation on /users, got %#v", doc.Paths)
+	}
+
+	if post.Summary != "Create user" {
+		t.Errorf("Invalid summary, expected %s, got %s", "Create user", post.Summary)
+	}
+
+	ref := &Schema{Ref: "#/components/schemas/openAPIUser"}
+
+	if s := post.RequestBody.Content["application/json"].Schema; !reflect.DeepEqual(s, ref) {
+		t.Errorf("Invalid request schema, expected %#v, got %#v", ref, s)
+	}
+
+	created := post.Responses["201"]
+
+	if created == nil || len(created.Content) != 2 {
+		t.Fatalf("Expected 201 response with 2 media types, got %#v", created)
+	}
+
+	if s := created.Content["application/msgpack"].Schema; !reflect.DeepEqual(s, ref) {
+		t.Errorf("Invalid response schema, expected %#v, got %#v", ref, s)
+	}
+
+	errRef := &Schema{Ref: "#/components/schemas/JSONError"}
+
+	for _, code := range []string{"400", "default"} {
+		if r := post.Responses[code]; r == nil || !reflect.DeepEqual(r.Content["application/json"].Schema, errRef) {
+			t.Errorf("Invalid %s response, expected JSONError schema, got %#v", code, r)
+		}
+	}
+
+	user := doc.Components.Schemas["openAPIUser"]
+
+	expectedUser := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":      {Type: "integer", Format: "int64"},
+			"name":    {Type: "string"},
+			"email":   {Type: "string"},
+			"created": {Type: "string", Format: "date-time"},
+			"tags":    {Type: "array", Items: &Schema{Type: "string"}},
+			"parent":  ref,
+			"meta":    {Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int64"}},
+		},
+		Required: []string{"created", "id", "name", "tags"},
+	}
+
+	if !reflect.DeepEqual(user, expectedUser) {
+		b, _ := json.Marshal(user)
+		t.Errorf("Invalid user schema, got %s", b)
+	}
+
+	if _, ok := doc.Components.Schemas["JSONError"]; !ok {
+		t.Errorf("Expected JSONError schema in components")
+	}
+
+	del := doc.Paths["/users/{id}/{rest}"]["delete"]
+
+	if del == nil {
+		t.Fatalf("Expected delete operation on /users/{id}/{rest}, got %#v", doc.Paths)
+	}
+
+	expectedParams := []*OpenAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "force", In: "query", Schema: &Schema{Type: "boolean"}},
+		{Name: "rest", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+	}
+
+	if !reflect.DeepEqual(del.Parameters, expectedParams) {
+		b, _ := json.Marshal(del.Parameters)
+		t.Errorf("Invalid parameters, got %s", b)
+	}
+
+	if r := del.Responses["204"]; r == nil || r.Content != nil {
+		t.Errorf("Expected 204 response without content, got %#v", r)
+	}
+}
+
+func TestRegistry_Serve(t *testing.T) {
+	reg := NewRegistry("Test", "1.0.0")
+
+	router := httprouter.New()
+
+	reg.Handle(router, Route{
+		Method:   http.MethodGet,
+		Path:     "/users",
+		Handler:  func(r *Request) interface{} { return []string{"a"} },
+		Types:    []ResponseType{responsetype.TypeJSON},
+		Response: []string{},
+	})
+
+	reg.Serve(router, "/openapi.json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if b := w.Body.String(); b != `["a"]` {
+		t.Errorf("Invalid route body, expected %s, got %s", `["a"]`, b)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Invalid content type, expected %s, got %s", "application/json", ct)
+	}
+
+	doc := &OpenAPIDocument{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatalf("Invalid document, got error %v", err)
+	}
+
+	if doc.Paths["/users"]["get"] == nil {
+		t.Errorf("Expected get operation on /users, got %s", w.Body.String())
+	}
+}
+
+type Result struct {
+	Local bool `json:"local"`
+}
+
+type openAPIPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestSchemaGenerator_ComponentNames(t *testing.T) {
+	g := &schemaGenerator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+
+	expected := []struct {
+		t   reflect.Type
+		ref string
+	}{
+		{reflect.TypeOf(Result{}), "#/components/schemas/Result"},
+		{reflect.TypeOf(responsetype.Result{}), "#/components/schemas/responsetype.Result"},
+		{reflect.TypeOf(&Result{}), "#/components/schemas/Result"},
+		{reflect.TypeOf(openAPIPage[openAPIUser]{}), "#/components/schemas/openAPIPage_responsewriter.openAPIUser"},
+	}
+
+	for _, e := range expected {
+		if s := g.schema(e.t); s.Ref != e.ref {
+			t.Errorf("Invalid schema reference of %s, expected %s, got %s", e.t, e.ref, s.Ref)
+		}
+	}
+
+	if len(g.schemas) != 4 {
+		t.Errorf("Expected 4 component schemas, got %d", len(g.schemas))
+	}
+}
@@ -0,0 +1,181 @@
+package responsewriter
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter decides whether a request identified by the key may be served.
+// Implementations backed by a shared store allow limiting across instances.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (RateLimit, error)
+}
+
+// RateLimit is the state of the limit of a key after taking a request into account.
+type RateLimit struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully restored
+	Reset time.Duration
+	// RetryAfter is the time until a request is allowed again, when not allowed
+	RetryAfter time.Duration
+}
+
+// KeyFunc returns the rate limiting key of a request.
+type KeyFunc func(r *Request) string
+
+// KeyByIP limits by the remote address of the request. Behind a proxy, use a key function
+// reading the client address set by the proxy.
+func KeyByIP(r *Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// KeyByRoute limits by the route, or the path when the route is unknown.
+func KeyByRoute(r *Request) string {
+	if len(r.Route) > 0 {
+		return r.Method + " " + r.Route
+	}
+
+	return r.Method + " " + r.URL.Path
+}
+
+// KeyByIPAndRoute limits every client per route.
+func KeyByIPAndRoute(r *Request) string {
+	return KeyByIP(r) + " " + KeyByRoute(r)
+}
+
+// RateLimitMiddleware answers requests over the limit with 429 Too Many Requests in the
+// negotiated response type. Every response carries the RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers, a 429 also Retry-After. When the limiter fails the request is served.
+func RateLimitMiddleware(limiter RateLimiter, key KeyFunc) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request) interface{} {
+			rl, err := limiter.Allow(r.Context(), key(r))
+
+			if err != nil {
+				r.logger().WithError(err).Error("Rate limiter failed, serving request")
+
+				return next(r)
+			}
+
+			h := r.ResponseHeader()
+			h.Set("RateLimit-Limit", strconv.Itoa(rl.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(rl.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(rl.Reset)))
+
+			if rl.Allowed {
+				return next(r)
+			}
+
+			h.Set("Retry-After", strconv.Itoa(seconds(rl.RetryAfter)))
+
+			return errorReply(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests), nil)
+		}
+	}
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
+
+// TokenBucket is an in memory RateLimiter, allowing bursts up to the limit and
+// restoring the quota evenly over the period.
+type TokenBucket struct {
+	limit  int
+	period time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket allows limit requests per period for every key
+func NewTokenBucket(limit int, period time.Duration) *TokenBucket {
+	if limit <= 0 || period <= 0 {
+		panic("Invalid token bucket limit or period")
+	}
+
+	return &TokenBucket{
+		limit:   limit,
+		period:  period,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (tb *TokenBucket) Allow(_ context.Context, key string) (RateLimit, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+
+	tb.prune(now)
+
+	b, ok := tb.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: float64(tb.limit), last: now}
+		tb.buckets[key] = b
+	}
+
+	rate := float64(tb.limit) / tb.period.Seconds()
+
+	b.tokens = math.Min(float64(tb.limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	rl := RateLimit{Limit: tb.limit}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		rl.Allowed = true
+	} else {
+		rl.RetryAfter = duration((1 - b.tokens) / rate)
+	}
+
+	rl.Remaining = int(b.tokens)
+	rl.Reset = duration((float64(tb.limit) - b.tokens) / rate)
+
+	return rl, nil
+}
+
+// prune removes buckets which are full again, at most once per period
+func (tb *TokenBucket) prune(now time.Time) {
+	if now.Sub(tb.pruned) < tb.period {
+		return
+	}
+
+	tb.pruned = now
+
+	for key, b := range tb.buckets {
+		if now.Sub(b.last) >= tb.period {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,117 @@
+package responsewriter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+	"time"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string) (RateLimit, error) {
+	return RateLimit{}, errors.New("testerror")
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tb := NewTokenBucket(2, time.Minute)
+	tb.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	for i, expected := range []int{1, 0} {
+		rl, _ := tb.Allow(ctx, "a")
+
+		if !rl.Allowed || rl.Remaining != expected || rl.Limit != 2 {
+			t.Errorf("Invalid rate limit for request %d, got %#v", i, rl)
+		}
+	}
+
+	rl, _ := tb.Allow(ctx, "a")
+
+	if rl.Allowed || rl.RetryAfter != 30*time.Second || rl.Reset != time.Minute {
+		t.Errorf("Invalid rate limit over the limit, got %#v", rl)
+	}
+
+	if rl, _ := tb.Allow(ctx, "b"); !rl.Allowed {
+		t.Errorf("Expected other keys to be limited separately")
+	}
+
+	now = now.Add(30 * time.Second)
+
+	if rl, _ := tb.Allow(ctx, "a"); !rl.Allowed || rl.Remaining != 0 {
+		t.Errorf("Expected a restored token after half the period, got %#v", rl)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	tb.Allow(ctx, "c")
+
+	if _, ok := tb.buckets["a"]; ok {
+		t.Errorf("Expected idle buckets to be pruned")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tb := NewTokenBucket(1, time.Minute)
+
+	rt := NewRouter(responsetype.TypeJSON, responsetype.TypeCSV).Use(RateLimitMiddleware(tb, KeyByIPAndRoute))
+
+	rt.GET("/a", func(r *Request) interface{} { return "ok" })
+
+	w := serveRouter(rt, http.MethodGet, "/a", "")
+
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("Invalid allowed response, got %d %v", w.Code, w.Header())
+	}
+
+	w = serveRouter(rt, http.MethodGet, "/a", "text/csv")
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	if ra := w.Header().Get("Retry-After"); ra != "60" {
+		t.Errorf("Invalid retry after header, expected %s, got %s", "60", ra)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Invalid content type, expected %s, got %s", "text/csv", ct)
+	}
+
+	w = serveRouter(rt, http.MethodGet, "/a", "")
+
+	if b := w.Body.String(); b != `{"code":429,"description":"Too Many Requests"}` {
+		t.Errorf("Invalid body, expected %s, got %s", `{"code":429,"description":"Too Many Requests"}`, b)
+	}
+
+	fn := RateLimitMiddleware(failingLimiter{}, KeyByIP)(func(r *Request) interface{} { return "ok" })
+
+	if res := fn(NewRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil)); res != "ok" {
+		t.Errorf("Expected the request to be served when the limiter fails, got %v", res)
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	r := NewRequest(httptest.NewRequest(http.MethodGet, "/users/1", nil), nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	if k := KeyByIP(r); k != "10.0.0.1" {
+		t.Errorf("Invalid ip key, expected %s, got %s", "10.0.0.1", k)
+	}
+
+	if k := KeyByRoute(r); k != "GET /users/1" {
+		t.Errorf("Invalid route key, expected %s, got %s", "GET /users/1", k)
+	}
+
+	r.Route = "/users/:id"
+
+	if k := KeyByIPAndRoute(r); k != "10.0.0.1 GET /users/:id" {
+		t.Errorf("Invalid ip and route key, expected %s, got %s", "10.0.0.1 GET /users/:id", k)
+	}
+}
@@ -0,0 +1,82 @@
+package responsewriter
+
+import (
+	"net/http"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"time"
+)
+
+// Reply is a format neutral response, understood by every response type.
+// Handlers can set a status code, body and headers without choosing a media type.
+type Reply struct {
+	code     int
+	body     interface{}
+	err      error
+	header   http.Header
+	cacheTTL time.Duration
+}
+
+// Respond creates a reply with a status code and body.
+// The body is encoded as is, e.g. an int is served as number instead of status code.
+func Respond(code int, body interface{}) *Reply {
+	return &Reply{
+		code: code,
+		body: body,
+	}
+}
+
+// Status creates a reply with a status code, without body.
+func Status(code int) *Reply {
+	return &Reply{
+		code: code,
+	}
+}
+
+// Body sets the body of the reply.
+func (r *Reply) Body(body interface{}) *Reply {
+	r.body = body
+
+	return r
+}
+
+// Header adds a response header.
+func (r *Reply) Header(key, value string) *Reply {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+
+	r.header.Add(key, value)
+
+	return r
+}
+
+// WithError attaches the error which caused the reply.
+func (r *Reply) WithError(err error) *Reply {
+	r.err = err
+
+	return r
+}
+
+// Cache marks the reply as cacheable by the Cache middleware for the duration.
+func (r *Reply) Cache(ttl time.Duration) *Reply {
+	r.cacheTTL = ttl
+
+	return r
+}
+
+func (r *Reply) CacheTTL() time.Duration {
+	return r.cacheTTL
+}
+
+func (r *Reply) GetCode() int {
+	return r.code
+}
+
+func (r *Reply) ToResult() *responsetype.Result {
+	return &responsetype.Result{
+		Code:    r.code,
+		Payload: r.body,
+		Err:     r.err,
+		Header:  r.header,
+	}
+}
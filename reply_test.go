@@ -0,0 +1,80 @@
+package responsewriter
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"testing"
+)
+
+func TestReply_ToResult(t *testing.T) {
+	err := errors.New("testerror")
+
+	r := Status(http.StatusCreated).Body([]int{1}).Header("X-Test", "a").Header("X-Test", "b").WithError(err)
+
+	expected := &responsetype.Result{
+		Code:    http.StatusCreated,
+		Payload: []int{1},
+		Err:     err,
+		Header:  http.Header{"X-Test": []string{"a", "b"}},
+	}
+
+	if got := r.ToResult(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Invalid reply result, expected %#v, got %#v", expected, got)
+	}
+
+	if c := r.GetCode(); c != http.StatusCreated {
+		t.Errorf("Invalid reply code, expected %d, got %d", http.StatusCreated, c)
+	}
+
+	expected = &responsetype.Result{Code: http.StatusAccepted, Payload: 12}
+
+	if got := Respond(http.StatusAccepted, 12).ToResult(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Invalid reply result, expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestReply_ResponseTypes(t *testing.T) {
+	mh := &mockHandler{i: Respond(http.StatusCreated, []string{"a", "b"}).Header("Location", "/a")}
+
+	fn := ResponseHandler(mh.fn, responsetype.TypeJSON, responsetype.TypeCSV, responsetype.TypeNDJSON, responsetype.TypeMsgPack)
+
+	expected := map[string]string{
+		"application/json":     "[\"a\",\"b\"]",
+		"text/csv":             "a\nb\n",
+		"application/x-ndjson": "\"a\"\n\"b\"\n",
+		"application/msgpack":  "\x92\xa1a\xa1b",
+	}
+
+	for accept, eb := range expected {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept", accept)
+
+		fn(w, r, httprouter.Params{})
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Invalid status code for %s, expected %d, got %d", accept, http.StatusCreated, w.Code)
+		}
+
+		if l := w.Header().Get("Location"); l != "/a" {
+			t.Errorf("Invalid location header for %s, expected %s, got %s", accept, "/a", l)
+		}
+
+		if b := w.Body.String(); b != eb {
+			t.Errorf("Invalid body for %s, expected %q, got %q", accept, eb, b)
+		}
+	}
+
+	mh.i = Status(http.StatusAccepted)
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest(http.MethodPost, "/", nil), httprouter.Params{})
+
+	if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("Invalid status reply, expected %d without body, got %d %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+}
@@ -2,13 +2,55 @@ package responsewriter
 
 import (
 	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
 	"net/http"
+	"strings"
 )
 
+// ParamFunc extracts a path parameter from the request, e.g. chi.URLParam.
+type ParamFunc func(r *http.Request, name string) string
+
 type Request struct {
 	*http.Request
 
+	// Params are the path parameters of httprouter or an http.ServeMux pattern,
+	// empty for other routers, Param reads those too
 	Params httprouter.Params
+
+	// RequestID identifies the request, set when the Writer handles request IDs
+	RequestID string
+	// Route is the route pattern of the Writer, empty when unknown
+	Route string
+
+	paramFunc ParamFunc
+	header    http.Header
+	principal *Principal
+
+	// responseType is the negotiated response type
+	responseType ResponseType
+	// cache stores the response, set by the Cache middleware
+	cache *cacheCapture
+	// idempotency stores the response, set by the Idempotency middleware
+	idempotency *idempotentRequest
+}
+
+// ResponseHeader returns headers added to the response, e.g. by middleware.
+// Headers set by the response itself take precedence.
+func (r *Request) ResponseHeader() http.Header {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+
+	return r.header
+}
+
+// logger returns the package logger including the request ID
+func (r *Request) logger() logrus.FieldLogger {
+	if len(r.RequestID) == 0 {
+		return log
+	}
+
+	return log.WithField("request_id", r.RequestID)
 }
 
 func NewRequest(r *http.Request, p httprouter.Params) *Request {
@@ -17,3 +59,43 @@ func NewRequest(r *http.Request, p httprouter.Params) *Request {
 		Params:  p,
 	}
 }
+
+// Param returns the path parameter, looking in the httprouter params, the
+// parameter function of the adapter and the http.ServeMux path values in that order.
+func (r *Request) Param(name string) string {
+	if v := r.Params.ByName(name); len(v) > 0 {
+		return v
+	}
+
+	if r.paramFunc != nil {
+		if v := r.paramFunc(r.Request, name); len(v) > 0 {
+			return v
+		}
+	}
+
+	return r.PathValue(name)
+}
+
+// patternParams returns the wildcards of the http.ServeMux pattern matching the request,
+// nil without pattern
+func patternParams(r *http.Request) httprouter.Params {
+	var params httprouter.Params
+
+	pattern := r.Pattern
+
+	for {
+		start := strings.IndexByte(pattern, '{')
+		end := strings.IndexByte(pattern, '}')
+
+		if start < 0 || end < start {
+			return params
+		}
+
+		name := strings.TrimSuffix(pattern[start+1:end], "...")
+		pattern = pattern[end+1:]
+
+		if len(name) > 0 && name != "$" {
+			params = append(params, httprouter.Param{Key: name, Value: r.PathValue(name)})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package responsewriter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is read from the request and echoed in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of request IDs accepted from clients
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// WithRequestID returns a writer reading the X-Request-ID header of every request, or
+// generating one when it is missing or invalid. The ID is stored in the request context and
+// on the Request, echoed in the response header and included in error bodies and log entries.
+// The generator defaults to NewRequestID.
+func (wr *Writer) WithRequestID(generate func() string) *Writer {
+	if generate == nil {
+		generate = NewRequestID
+	}
+
+	c := *wr
+	c.requestID = generate
+
+	return &c
+}
+
+// NewRequestID generates a random 128 bit request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFromContext returns the request ID stored by the Writer, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// bindRequestID reads or generates the request ID and echoes it
+func (wr *Writer) bindRequestID(w http.ResponseWriter, r *Request) {
+	id := r.Header.Get(RequestIDHeader)
+
+	if !isValidRequestID(id) {
+		id = wr.requestID()
+	}
+
+	r.RequestID = id
+	r.Request = r.Request.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+	w.Header().Set(RequestIDHeader, id)
+}
+
+// isValidRequestID accepts non-empty IDs of printable ASCII characters, which are safe to echo and log
+func isValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,139 @@
+package responsewriter
+
+import (
+	"bytes"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriter_RequestID(t *testing.T) {
+	var got, fromContext string
+
+	wr := NewWriter().WithRequestID(func() string { return "generated" })
+
+	fn := wr.Handler(func(r *Request) interface{} {
+		got = r.RequestID
+		fromContext = RequestIDFromContext(r.Context())
+
+		return errors.New("testerror")
+	}, responsetype.TypeJSON, responsetype.TypeMsgPack)
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "generated"},
+		{"abc-123", "abc-123"},
+		{"with space", "generated"},
+		{strings.Repeat("a", maxRequestIDLength+1), "generated"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if len(test.header) > 0 {
+			r.Header.Set(RequestIDHeader, test.header)
+		}
+
+		fn(w, r, httprouter.Params{})
+
+		if got != test.expected || fromContext != test.expected {
+			t.Errorf("Invalid request id for %q, expected %s, got %s and %s from the context", test.header, test.expected, got, fromContext)
+		}
+
+		if h := w.Header().Get(RequestIDHeader); h != test.expected {
+			t.Errorf("Invalid request id header for %q, expected %s, got %s", test.header, test.expected, h)
+		}
+
+		eb := `{"code":500,"description":"Internal Server Error","request_id":"` + test.expected + `"}`
+
+		if b := w.Body.String(); b != eb {
+			t.Errorf("Invalid body for %q, expected %s, got %s", test.header, eb, b)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/msgpack")
+
+	fn(w, r, httprouter.Params{})
+
+	if !bytes.Contains(w.Body.Bytes(), []byte("generated")) {
+		t.Errorf("Expected the request id in the msgpack error body, got %q", w.Body.String())
+	}
+}
+
+func TestWriter_RequestIDShared(t *testing.T) {
+	shared := responsetype.NewJSONError(http.StatusNotFound, "not found", nil)
+
+	fn := NewWriter().WithRequestID(nil).Handler(func(r *Request) interface{} { return shared }, responsetype.TypeJSON)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(id string) {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(RequestIDHeader, id)
+
+			fn(w, r, httprouter.Params{})
+
+			if !strings.Contains(w.Body.String(), `"request_id":"`+id+`"`) {
+				t.Errorf("Invalid body for %s, got %s", id, w.Body.String())
+			}
+		}("id-" + strconv.Itoa(i))
+	}
+
+	wg.Wait()
+
+	if _, b := shared.Handle(); strings.Contains(string(b), "request_id") {
+		t.Errorf("Expected the shared response to be unchanged, got %s", b)
+	}
+}
+
+func TestWriter_RequestIDLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	l := logrus.New()
+	l.Out = buf
+
+	orig := log
+	defer func() { log = orig }()
+
+	log = l.WithField("test", true)
+
+	fn := NewWriter().WithRequestID(nil).Handler(func(r *Request) interface{} {
+		return make(chan int)
+	}, responsetype.TypeJSON)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "log-id")
+
+	fn(w, r, httprouter.Params{})
+
+	if !strings.Contains(buf.String(), "request_id=log-id") {
+		t.Errorf("Expected the request id in the log entry, got %s", buf.String())
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+
+	if len(a) != 32 || a == b || !isValidRequestID(a) {
+		t.Errorf("Invalid request ids, got %s and %s", a, b)
+	}
+}
@@ -0,0 +1,29 @@
+package responsetype
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR encodes payloads as CBOR (RFC 8949).
+// Struct fields are named by their json tags and json.Marshaler values are encoded
+// as the document they marshal to, so clients receive the same structure as JSON.
+type CBOR struct{}
+
+var (
+	// Canonical (RFC 7049) key ordering, for output identical between requests
+	cborEncMode, _ = cbor.CanonicalEncOptions().EncMode()
+)
+
+func (CBOR) ContentType() string {
+	return "application/cbor"
+}
+
+func (CBOR) Marshal(payload interface{}) ([]byte, error) {
+	v, err := jsonNative(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cborEncMode.Marshal(v)
+}
@@ -0,0 +1,183 @@
+package responsetype
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// Result is the format neutral classification of a handler's return value.
+// A nil Payload means the response has no body.
+type Result struct {
+	Code    int
+	Payload interface{}
+	Err     error
+	Header  http.Header
+}
+
+// Resulter is implemented by values classifying themselves, e.g. format neutral responses.
+type Resulter interface {
+	ToResult() *Result
+}
+
+// Encoder encodes the payload of a Result for a single media type.
+// Wrapped in an EncoderType, it is a complete response type.
+type Encoder interface {
+	ContentType() string
+	Marshal(payload interface{}) ([]byte, error)
+}
+
+// Classify turns a handler's return value into a Result, following the rules
+// shared by all response types. It returns nil for values it does not recognize.
+//
+//	nil, "", nil pointer      204 without body
+//	string                    200, the string as (pre-encoded) body
+//	Result, *Result, Resulter as is
+//	JSONError, *JSONError     its code, the error as body
+//	int, int8-64, uint-uint64 the value as status code, without body
+//	error                     500 or the code of a Coder, a JSONError as body,
+//	                          unless the error implements json.Marshaler
+//	json.Marshaler            200 or the code of a Coder, the value as body
+//	map, slice, array         200, the value as body
+//	struct, pointer           200 or the code of a Coder, the value as body
+//	bool, float               200, the value as body
+//	named string, integer     200 or the code of a Coder, the value as body
+//
+// Only the builtin integer types are status codes, to serve a number as body
+// return a Result or a named type (e.g. `type Count int`). Handlers serve the
+// default error for codes outside 100-599.
+// Channels, functions and complex numbers are not recognized.
+func Classify(resp interface{}) *Result {
+	if resp == nil {
+		return &Result{Code: http.StatusNoContent}
+	}
+
+	if res, ok := resultOf(resp); ok {
+		return res
+	}
+
+	switch cResp := resp.(type) {
+	case string:
+		if len(cResp) == 0 {
+			return &Result{Code: http.StatusNoContent}
+		}
+
+		return &Result{Code: http.StatusOK, Payload: cResp}
+	case JSONError:
+		return &Result{Code: cResp.Code, Payload: cResp, Err: cResp.Err}
+	case *JSONError:
+		return &Result{Code: cResp.Code, Payload: *cResp, Err: cResp.Err}
+	case int:
+		return &Result{Code: cResp}
+	case int32:
+		return &Result{Code: int(cResp)}
+	case int16:
+		return &Result{Code: int(cResp)}
+	case int8:
+		return &Result{Code: int(cResp)}
+	case uint16:
+		return &Result{Code: int(cResp)}
+	case uint8:
+		return &Result{Code: int(cResp)}
+	case int64:
+		return &Result{Code: int(cResp)}
+	case uint:
+		return &Result{Code: int(cResp)}
+	case uint32:
+		return &Result{Code: int(cResp)}
+	case uint64:
+		return &Result{Code: int(cResp)}
+	}
+
+	cod, codOk := resp.(Coder)
+
+	// We assume an error adhering to the json.Marshaler interface
+	// will be consumable for public r
+	if err, ok := resp.(error); ok {
+		res := &Result{Code: http.StatusInternalServerError, Payload: defaultJSONError(), Err: err}
+
+		if codOk {
+			res.Code = cod.GetCode()
+		}
+
+		if _, marOk := resp.(json.Marshaler); marOk {
+			res.Payload = resp
+		} else if codOk {
+			je := defaultJSONError()
+			je.Code = res.Code
+			je.Err = err
+
+			res.Payload = je
+		}
+
+		return res
+	}
+
+	if _, ok := resp.(json.Marshaler); ok {
+		res := &Result{Code: http.StatusOK, Payload: resp}
+
+		if codOk {
+			res.Code = cod.GetCode()
+		}
+
+		return res
+	}
+
+	rv := reflect.ValueOf(resp)
+
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Bool, reflect.Float32, reflect.Float64:
+		return &Result{Code: http.StatusOK, Payload: resp}
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return &Result{Code: http.StatusNoContent}
+		}
+	case reflect.Struct, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil
+	}
+
+	res := &Result{Code: http.StatusOK, Payload: resp}
+
+	if codOk {
+		res.Code = cod.GetCode()
+	}
+
+	return res
+}
+
+// resultOf returns the result of values which are (or produce) a Result
+func resultOf(resp interface{}) (*Result, bool) {
+	switch cResp := resp.(type) {
+	case Result:
+		return &cResp, true
+	case *Result:
+		return cResp, cResp != nil
+	case Resulter:
+		res := cResp.ToResult()
+
+		return res, res != nil
+	}
+
+	return nil, false
+}
+
+// ErrorPayload returns the payload as JSONError, for formats which can not use
+// an error's own encoding. The second return value is false for non-errors.
+func (r *Result) ErrorPayload() (JSONError, bool) {
+	if je, ok := r.Payload.(JSONError); ok {
+		return je, true
+	}
+
+	if r.Err == nil {
+		return JSONError{}, false
+	}
+
+	return JSONError{
+		Code:        r.Code,
+		Description: CodeToStatus(r.Code),
+		Err:         r.Err,
+	}, true
+}
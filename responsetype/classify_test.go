@@ -0,0 +1,98 @@
+package responsetype
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type namedInt int
+
+type namedString string
+
+type coderStruct struct {
+	code int
+}
+
+func (cs coderStruct) GetCode() int {
+	return cs.code
+}
+
+func TestClassify(t *testing.T) {
+	err := errors.New("testerror")
+	res := &Result{Code: http.StatusCreated, Payload: "test"}
+
+	expected := []struct {
+		resp interface{}
+		res  *Result
+	}{
+		{nil, &Result{Code: http.StatusNoContent}},
+		{"", &Result{Code: http.StatusNoContent}},
+		{"test", &Result{Code: http.StatusOK, Payload: "test"}},
+		{res, res},
+		{*res, res},
+		{JSONError{Code: http.StatusNotFound}, &Result{Code: http.StatusNotFound, Payload: JSONError{Code: http.StatusNotFound}}},
+		{&JSONError{Code: http.StatusNotFound, Err: err}, &Result{Code: http.StatusNotFound, Payload: JSONError{Code: http.StatusNotFound, Err: err}, Err: err}},
+		{int(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{int32(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{int16(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{int8(10), &Result{Code: 10}},
+		{uint16(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{uint8(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{err, &Result{Code: http.StatusInternalServerError, Payload: defaultJSONError(), Err: err}},
+		{CoderError(http.StatusConflict), &Result{Code: http.StatusConflict, Payload: JSONError{Code: http.StatusConflict, Description: "Internal Server Error", Err: CoderError(http.StatusConflict)}, Err: CoderError(http.StatusConflict)}},
+		{JSONMarshalError("x"), &Result{Code: http.StatusInternalServerError, Payload: JSONMarshalError("x"), Err: JSONMarshalError("x")}},
+		{JSONMarshalCoderMock("x"), &Result{Code: 1, Payload: JSONMarshalCoderMock("x")}},
+		{JSONMarshalMock("x"), &Result{Code: http.StatusOK, Payload: JSONMarshalMock("x")}},
+		{[]int{1}, &Result{Code: http.StatusOK, Payload: []int{1}}},
+		{[1]int{1}, &Result{Code: http.StatusOK, Payload: [1]int{1}}},
+		{map[string]int{}, &Result{Code: http.StatusOK, Payload: map[string]int{}}},
+		{int64(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{uint(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{uint32(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{uint64(http.StatusAccepted), &Result{Code: http.StatusAccepted}},
+		{true, &Result{Code: http.StatusOK, Payload: true}},
+		{1.5, &Result{Code: http.StatusOK, Payload: 1.5}},
+		{float32(1.5), &Result{Code: http.StatusOK, Payload: float32(1.5)}},
+		{struct{}{}, &Result{Code: http.StatusOK, Payload: struct{}{}}},
+		{&csvRow{Name: "x"}, &Result{Code: http.StatusOK, Payload: &csvRow{Name: "x"}}},
+		{(*csvRow)(nil), &Result{Code: http.StatusNoContent}},
+		{namedInt(3), &Result{Code: http.StatusOK, Payload: namedInt(3)}},
+		{namedString("x"), &Result{Code: http.StatusOK, Payload: namedString("x")}},
+		{coderStruct{code: http.StatusCreated}, &Result{Code: http.StatusCreated, Payload: coderStruct{code: http.StatusCreated}}},
+		{make(chan int), nil},
+		{func() {}, nil},
+		{complex(1, 2), nil},
+	}
+
+	for _, e := range expected {
+		got := Classify(e.resp)
+
+		if !reflect.DeepEqual(got, e.res) {
+			t.Errorf("Invalid classification of %#v, expected %#v, got %#v", e.resp, e.res, got)
+		}
+	}
+}
+
+func TestResult_ErrorPayload(t *testing.T) {
+	je, ok := (&Result{Code: http.StatusOK, Payload: "test"}).ErrorPayload()
+
+	if ok {
+		t.Errorf("Expected no error payload, got %#v", je)
+	}
+
+	expected := JSONError{Code: http.StatusNotFound, Description: "test"}
+	je, ok = (&Result{Code: http.StatusNotFound, Payload: expected}).ErrorPayload()
+
+	if !ok || !reflect.DeepEqual(je, expected) {
+		t.Errorf("Invalid error payload, expected %#v, got %#v", expected, je)
+	}
+
+	expected = JSONError{Code: 3, Description: "Unknown error", Err: JSONMarshalCoderError("abc")}
+	je, ok = Classify(JSONMarshalCoderError("abc")).ErrorPayload()
+
+	if !ok || !reflect.DeepEqual(je, expected) {
+		t.Errorf("Invalid error payload for marshaler error, expected %#v, got %#v", expected, je)
+	}
+}
@@ -0,0 +1,598 @@
+package responsetype
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"peterdekok.nl/gotools/logger"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSV serves tabular data as comma separated values.
+//
+// Supported bodies are [][]string (written as is), sequences (slices, arrays,
+// channels and iterators) of structs, maps or slices, a single struct and a map.
+// Struct fields are written in declaration order, the `csv` tag renames a column
+// and `csv:"-"` skips it. Map columns are sorted by key, taken from the first row.
+// A plain map is written as key,value rows.
+type CSV struct {
+	Code      int
+	Body      interface{}
+	err       error
+	log       logger.Logger
+	opts      *CSVOptions
+	hdr       http.Header
+	requestID string
+}
+
+// CSVOptions configure the CSV encoding.
+type CSVOptions struct {
+	// Comma is the field delimiter, defaults to ','.
+	Comma rune
+
+	// Filename is sent in the Content-Disposition header, prompting a download.
+	Filename string
+
+	// Stream flushes every row as soon as it is written, instead of buffering the body.
+	Stream bool
+
+	// EscapeFormulas prefixes cells starting with =, +, -, @, tab or carriage return with a quote,
+	// so spreadsheets do not evaluate them as formula. Numbers are written as is.
+	EscapeFormulas bool
+}
+
+type CSVResponsable interface {
+	ToCSV() *CSV
+}
+
+// csvStream is a CSV response written row by row
+type csvStream struct {
+	*CSV
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func NewCSV(code int, body interface{}) *CSV {
+	return &CSV{
+		Code: code,
+		Body: body,
+	}
+}
+
+func (r *CSV) WithError(err error) *CSV {
+	r.err = err
+
+	return r
+}
+
+func (r *CSV) WithLogger(log logger.Logger) *CSV {
+	r.log = log
+
+	return r
+}
+
+// WithOptions sets the encoding options.
+// Used on a response type, the options are inherited by all responses it unmarshals.
+func (r *CSV) WithOptions(opts CSVOptions) *CSV {
+	r.opts = &opts
+
+	return r
+}
+
+// WithFilename serves the response as a download with the given filename.
+func (r *CSV) WithFilename(filename string) *CSV {
+	opts := CSVOptions{}
+
+	if r.opts != nil {
+		opts = *r.opts
+	}
+
+	opts.Filename = filename
+
+	return r.WithOptions(opts)
+}
+
+func (r CSV) GetCode() int {
+	return r.Code
+}
+
+func (r CSV) GetBody() []byte {
+	_, b := r.encode()
+
+	return b
+}
+
+func (r CSV) Handle() (int, []byte) {
+	c := r.GetCode()
+	ec, b := r.encode()
+
+	if ec != 0 {
+		c = ec
+	} else if c == 0 && len(b) == 0 {
+		c = http.StatusInternalServerError
+		b = r.errorTable(defaultJSONError())
+	} else if c == 0 {
+		c = http.StatusOK
+	}
+
+	if r.err != nil && r.log != nil {
+		r.log.WithFields(logFields(c, r.requestID)).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
+	}
+
+	return c, b
+}
+
+func (r CSV) GetContentType() string {
+	return "text/csv"
+}
+
+func (r CSV) GetError() error {
+	return r.err
+}
+
+func (r CSV) GetHeaders() http.Header {
+	if r.opts == nil || len(r.opts.Filename) == 0 {
+		return r.hdr
+	}
+
+	h := r.hdr.Clone()
+
+	if h == nil {
+		h = make(http.Header)
+	}
+
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": r.opts.Filename}))
+
+	return h
+}
+
+func (r *CSV) Unmarshal(resp interface{}) Response {
+	cResp := r.unmarshal(resp)
+
+	c, ok := cResp.(*CSV)
+
+	if !ok {
+		return cResp
+	}
+
+	// Copy before inheriting the options, the response might be shared between requests
+	if c.opts == nil && r.opts != nil {
+		cc := *c
+		cc.opts = r.opts
+		c = &cc
+	}
+
+	if c.opts != nil && c.opts.Stream {
+		return &csvStream{CSV: c}
+	}
+
+	return c
+}
+
+func (r *CSV) unmarshal(resp interface{}) Response {
+	if cr, ok := resp.(CSVResponsable); ok {
+		return cr.ToCSV()
+	}
+
+	switch cResp := resp.(type) {
+	case CSV:
+		return &cResp
+	case *CSV:
+		return cResp
+	}
+
+	res := Classify(resp)
+
+	if res == nil {
+		if isSequence(resp) || isTabular(reflect.TypeOf(resp)) {
+			return &CSV{Code: http.StatusOK, Body: resp}
+		}
+
+		return nil
+	}
+
+	if je, ok := res.ErrorPayload(); ok {
+		return &CSV{Code: res.Code, Body: je, err: res.Err, hdr: res.Header}
+	}
+
+	return &CSV{Code: res.Code, Body: res.Payload, err: res.Err, hdr: res.Header}
+}
+
+func (r *CSV) DefaultError() Response {
+	return r.defaultError()
+}
+
+func (r *CSV) defaultError() *CSV {
+	return &CSV{
+		Code: http.StatusInternalServerError,
+		Body: defaultJSONError(),
+		opts: r.opts,
+	}
+}
+
+func (r *CSV) GetAcceptedType() string {
+	return "text/csv"
+}
+
+func (r *CSV) String() string {
+	return r.GetAcceptedType()
+}
+
+// encode buffers the body, a failure replaces the body with an error table
+// and returns the error's code.
+func (r CSV) encode() (int, []byte) {
+	buf := &bytes.Buffer{}
+
+	if err := r.write(context.Background(), buf, false); err != nil {
+		requestLog(r.requestID).WithError(err).Warn("Failed to encode csv response")
+
+		je := errorToJSONError(err)
+
+		return je.Code, r.errorTable(je)
+	}
+
+	return 0, buf.Bytes()
+}
+
+// Stream writes and flushes the rows as they are produced.
+func (r *csvStream) Stream(w io.Writer) error {
+	return r.StreamContext(context.Background(), w)
+}
+
+// StreamContext writes and flushes the rows as they are produced, until the context is done.
+func (r *csvStream) StreamContext(ctx context.Context, w io.Writer) error {
+	return r.write(ctx, w, true)
+}
+
+func (r CSV) write(ctx context.Context, w io.Writer, flush bool) error {
+	switch b := r.Body.(type) {
+	case nil:
+		return nil
+	case string:
+		_, err := io.WriteString(w, b)
+
+		return err
+	case []byte:
+		_, err := w.Write(b)
+
+		return err
+	case JSONError:
+		_, err := w.Write(r.errorTable(b))
+
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+
+	if r.opts != nil && r.opts.Comma != 0 {
+		cw.Comma = r.opts.Comma
+	}
+
+	tw := &tableWriter{w: cw, flush: flush, escape: r.opts != nil && r.opts.EscapeFormulas}
+
+	if isSequence(r.Body) {
+		if err := eachInSequence(ctx, r.Body, tw.writeRow); err != nil && tw.err == nil {
+			return err
+		}
+	} else if rv := reflect.ValueOf(r.Body); rv.Kind() == reflect.Map {
+		tw.writeMap(rv)
+	} else {
+		tw.writeRow(r.Body)
+	}
+
+	if tw.err != nil {
+		return tw.err
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func (r CSV) errorTable(je JSONError) []byte {
+	buf := &bytes.Buffer{}
+
+	cw := csv.NewWriter(buf)
+
+	if r.opts != nil && r.opts.Comma != 0 {
+		cw.Comma = r.opts.Comma
+	}
+
+	_ = cw.WriteAll([][]string{
+		{"code", "description"},
+		{strconv.Itoa(je.Code), formatCell(reflect.ValueOf(je.Description))},
+	})
+
+	return buf.Bytes()
+}
+
+// tableWriter writes rows of any supported kind, the first row determines the columns
+type tableWriter struct {
+	w      *csv.Writer
+	flush  bool
+	escape bool
+	err    error
+
+	header  bool
+	columns []string
+}
+
+func (tw *tableWriter) writeRow(row interface{}) bool {
+	if err, ok := row.(error); ok {
+		tw.err = err
+
+		return false
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(row))
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		if _, ok := rv.Interface().(encoding.TextMarshaler); !ok {
+			tw.writeStruct(rv)
+
+			break
+		}
+
+		tw.write([]string{formatCell(rv)})
+	case reflect.Map:
+		tw.writeMapRow(rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			tw.write([]string{formatCell(rv)})
+
+			break
+		}
+
+		cells := make([]string, rv.Len())
+
+		for i := range cells {
+			cells[i] = formatCell(rv.Index(i))
+		}
+
+		tw.write(cells)
+	default:
+		tw.write([]string{formatCell(rv)})
+	}
+
+	return tw.err == nil
+}
+
+func (tw *tableWriter) writeStruct(rv reflect.Value) {
+	fields := csvFields(rv.Type())
+
+	if !tw.header {
+		header := make([]string, len(fields))
+
+		for i, f := range fields {
+			header[i] = f.name
+		}
+
+		tw.header = true
+
+		if !tw.write(header) {
+			return
+		}
+	}
+
+	cells := make([]string, len(fields))
+
+	for i, f := range fields {
+		cells[i] = formatCell(rv.FieldByIndex(f.index))
+	}
+
+	tw.write(cells)
+}
+
+func (tw *tableWriter) writeMapRow(rv reflect.Value) {
+	if !tw.header {
+		tw.columns = sortedKeys(rv)
+		tw.header = true
+
+		if !tw.write(tw.columns) {
+			return
+		}
+	}
+
+	// Match the columns by formatted key, the keys need not be strings
+	values := make(map[string]reflect.Value, rv.Len())
+
+	for _, k := range rv.MapKeys() {
+		values[formatCell(k)] = rv.MapIndex(k)
+	}
+
+	cells := make([]string, len(tw.columns))
+
+	for i, col := range tw.columns {
+		cells[i] = formatCell(values[col])
+	}
+
+	tw.write(cells)
+}
+
+// writeMap writes a single map as key,value rows
+func (tw *tableWriter) writeMap(rv reflect.Value) {
+	if !tw.write([]string{"key", "value"}) {
+		return
+	}
+
+	keys := rv.MapKeys()
+	cells := make(map[string]reflect.Value, len(keys))
+	names := make([]string, len(keys))
+
+	for i, k := range keys {
+		names[i] = formatCell(k)
+		cells[names[i]] = rv.MapIndex(k)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !tw.write([]string{name, formatCell(cells[name])}) {
+			return
+		}
+	}
+}
+
+func (tw *tableWriter) write(cells []string) bool {
+	if tw.err != nil {
+		return false
+	}
+
+	if tw.escape {
+		cells = escapeFormulas(cells)
+	}
+
+	if tw.err = tw.w.Write(cells); tw.err != nil {
+		return false
+	}
+
+	if tw.flush {
+		tw.w.Flush()
+		tw.err = tw.w.Error()
+	}
+
+	return tw.err == nil
+}
+
+type csvField struct {
+	name  string
+	index []int
+}
+
+// csvFields lists the exported fields of a struct, honouring the csv tag
+func csvFields(t reflect.Type) []csvField {
+	fields := make([]csvField, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		name := f.Name
+
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+
+			if len(tag) > 0 {
+				name = tag
+			}
+		}
+
+		fields = append(fields, csvField{name: name, index: f.Index})
+	}
+
+	return fields
+}
+
+func sortedKeys(rv reflect.Value) []string {
+	keys := make([]string, 0, rv.Len())
+
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, formatCell(k))
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// isTabular reports whether a single (non sequence) value can be written as a table
+func isTabular(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return !t.Implements(textMarshalerType) && !reflect.PtrTo(t).Implements(textMarshalerType)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	}
+
+	return false
+}
+
+// escapeFormulas returns the cells with a quote before cells a spreadsheet would evaluate
+func escapeFormulas(cells []string) []string {
+	escaped := make([]string, len(cells))
+
+	for i, c := range cells {
+		escaped[i] = c
+
+		if len(c) == 0 || !strings.ContainsRune("=+-@\t\r", rune(c[0])) {
+			continue
+		}
+
+		if _, err := strconv.ParseFloat(c, 64); err != nil {
+			escaped[i] = "'" + c
+		}
+	}
+
+	return escaped
+}
+
+func formatCell(rv reflect.Value) string {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return ""
+		}
+
+		if tm, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			return marshalText(tm)
+		}
+
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return ""
+	}
+
+	if rv.CanInterface() {
+		if tm, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			return marshalText(tm)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes())
+		}
+	}
+
+	return fmt.Sprint(rv.Interface())
+}
+
+func marshalText(tm encoding.TextMarshaler) string {
+	b, err := tm.MarshalText()
+
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
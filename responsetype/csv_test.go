@@ -0,0 +1,212 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type csvRow struct {
+	Name    string `csv:"name"`
+	Age     int    `csv:"age"`
+	Skipped string `csv:"-"`
+	Score   *float64
+	Created time.Time `csv:"created"`
+	hidden  string
+}
+
+type CSVResponsableMock struct {
+	code int
+	body interface{}
+}
+
+func (cr CSVResponsableMock) ToCSV() *CSV {
+	return &CSV{Code: cr.code, Body: cr.body}
+}
+
+func TestCSV_Unmarshal(t *testing.T) {
+	root := &CSV{}
+
+	score := 9.5
+	created := time.Date(2020, 6, 20, 12, 0, 0, 0, time.UTC)
+
+	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(""), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(complex(1, 2)), -1, nil)
+	expectResponse(t, root.Unmarshal(int16(http.StatusAccepted)), http.StatusAccepted, []byte(""))
+	expectResponse(t, root.Unmarshal("a,b\n"), http.StatusOK, []byte("a,b\n"))
+	expectResponse(t, root.Unmarshal(CSVResponsableMock{code: http.StatusCreated, body: "a\n"}), http.StatusCreated, []byte("a\n"))
+
+	expectResponse(t, root.Unmarshal([][]string{{"a", "b"}, {"c,d", "e\"f"}}), http.StatusOK, []byte("a,b\n\"c,d\",\"e\"\"f\"\n"))
+
+	rows := []csvRow{
+		{Name: "alice", Age: 30, Skipped: "x", Score: &score, Created: created, hidden: "y"},
+		{Name: "bob", Age: 40},
+	}
+
+	expectResponse(t, root.Unmarshal(rows), http.StatusOK, []byte("name,age,Score,created\nalice,30,9.5,2020-06-20T12:00:00Z\nbob,40,,0001-01-01T00:00:00Z\n"))
+	expectResponse(t, root.Unmarshal([]*csvRow{&rows[1]}), http.StatusOK, []byte("name,age,Score,created\nbob,40,,0001-01-01T00:00:00Z\n"))
+	expectResponse(t, root.Unmarshal(&rows[1]), http.StatusOK, []byte("name,age,Score,created\nbob,40,,0001-01-01T00:00:00Z\n"))
+
+	maps := []map[string]interface{}{{"b": 1, "a": "x"}, {"a": "y", "c": true}}
+
+	expectResponse(t, root.Unmarshal(maps), http.StatusOK, []byte("a,b\nx,1\ny,\n"))
+	expectResponse(t, root.Unmarshal([]map[int]string{{1: "a", 2: "b"}, {2: "c"}}), http.StatusOK, []byte("1,2\na,b\n,c\n"))
+	expectResponse(t, root.Unmarshal(map[string]int{"b": 2, "a": 1}), http.StatusOK, []byte("key,value\na,1\nb,2\n"))
+
+	expectResponse(t, root.Unmarshal(errors.New("testerror")), http.StatusInternalServerError, []byte("code,description\n500,Internal Server Error\n"))
+	expectResponse(t, root.Unmarshal(CoderError(http.StatusConflict)), http.StatusConflict, []byte("code,description\n409,Internal Server Error\n"))
+	expectResponse(t, root.Unmarshal(JSONError{Code: http.StatusNotFound, Description: "missing"}), http.StatusNotFound, []byte("code,description\n404,missing\n"))
+
+	ch := make(chan csvRow, 1)
+	ch <- rows[1]
+	close(ch)
+
+	expectResponse(t, root.Unmarshal(ch), http.StatusOK, []byte("name,age,Score,created\nbob,40,,0001-01-01T00:00:00Z\n"))
+}
+
+func TestCSV_WithOptions(t *testing.T) {
+	root := (&CSV{}).WithOptions(CSVOptions{Comma: ';'})
+
+	resp := root.Unmarshal([][]string{{"a", "b;c"}})
+
+	expectResponse(t, resp, http.StatusOK, []byte("a;\"b;c\"\n"))
+
+	if _, ok := resp.(Streamer); ok {
+		t.Error("Expected buffered response without stream option")
+	}
+
+	if h := resp.(Headerer).GetHeaders(); h != nil {
+		t.Errorf("Expected no headers without filename, got %v", h)
+	}
+
+	root.WithFilename("report 2020.csv")
+
+	if root.opts.Comma != ';' {
+		t.Error("Expected filename to keep existing options")
+	}
+
+	ecd := "attachment; filename=\"report 2020.csv\""
+	cd := root.Unmarshal("a\n").(Headerer).GetHeaders().Get("Content-Disposition")
+
+	if cd != ecd {
+		t.Errorf("Invalid content disposition, expected %s, got %s", ecd, cd)
+	}
+
+	shared := NewCSV(http.StatusOK, "a\n")
+	_ = root.Unmarshal(shared)
+
+	if shared.opts != nil {
+		t.Error("Expected returned responses not to be modified")
+	}
+}
+
+func TestCSV_EscapeFormulas(t *testing.T) {
+	type row struct {
+		Name   string `csv:"=name"`
+		Amount int
+	}
+
+	body := []row{{"=1+2", -5}, {"@SUM(A1)", 3}, {"+31 6", 0}, {"-x", 1}, {"a=b", 2}}
+
+	resp := (&CSV{}).WithOptions(CSVOptions{EscapeFormulas: true}).Unmarshal(body)
+
+	expectResponse(t, resp, http.StatusOK, []byte("'=name,Amount\n'=1+2,-5\n'@SUM(A1),3\n'+31 6,0\n'-x,1\na=b,2\n"))
+
+	resp = (&CSV{}).Unmarshal(body)
+
+	expectResponse(t, resp, http.StatusOK, []byte("=name,Amount\n=1+2,-5\n@SUM(A1),3\n+31 6,0\n-x,1\na=b,2\n"))
+}
+
+func TestCSV_Stream(t *testing.T) {
+	root := (&CSV{}).WithOptions(CSVOptions{Stream: true})
+
+	seq := func(yield func(interface{}) bool) {
+		_ = yield([]string{"a", "b"}) && yield([]string{"c", "d"}) && yield(CoderError(http.StatusConflict)) && yield([]string{"e"})
+	}
+
+	s, ok := root.Unmarshal(seq).(Streamer)
+
+	if !ok {
+		t.Fatal("Expected streaming response with stream option")
+	}
+
+	fw := &failingWriter{}
+
+	if err := s.Stream(fw); err == nil {
+		t.Error("Expected write error to be returned")
+	}
+
+	if fw.writes != 1 {
+		t.Errorf("Expected each row to be flushed, expected %d writes, got %d", 1, fw.writes)
+	}
+
+	buf := &bytes.Buffer{}
+	err := s.Stream(buf)
+
+	if _, ok := err.(CoderError); !ok {
+		t.Errorf("Expected stream error to be returned, got %v", err)
+	}
+
+	expected := []byte("a,b\nc,d\n")
+
+	if bytes.Compare(buf.Bytes(), expected) != 0 {
+		t.Errorf("Invalid streamed body, expected %s, got %s", expected, buf.Bytes())
+	}
+
+	expectResponse(t, (&CSV{}).Unmarshal(seq), http.StatusOK, []byte("code,description\n409,Conflict\n"))
+
+	c, _ := (&CSV{}).Unmarshal(seq).Handle()
+
+	if c != http.StatusConflict {
+		t.Errorf("Invalid code for failed buffered body, expected %d, got %d", http.StatusConflict, c)
+	}
+}
+
+func TestCSV_Handle(t *testing.T) {
+	c, b := (&CSV{}).Handle()
+
+	ec := http.StatusInternalServerError
+	eb := []byte("code,description\n500,Internal Server Error\n")
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+
+	c, b = NewCSV(0, "a\n").Handle()
+
+	ec = http.StatusOK
+	eb = []byte("a\n")
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+}
+
+func TestCSV_DefaultError(t *testing.T) {
+	expectResponse(t, (&CSV{}).DefaultError(), http.StatusInternalServerError, []byte("code,description\n500,Internal Server Error\n"))
+}
+
+func TestCSV_GetContentType(t *testing.T) {
+	expected := "text/csv"
+
+	if got := (&CSV{}).GetContentType(); got != expected {
+		t.Errorf("Invalid CSV content type, expected %s, got %s", expected, got)
+	}
+
+	if got := (&CSV{}).GetAcceptedType(); got != expected {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", expected, got)
+	}
+
+	if got := (&CSV{}).String(); got != expected {
+		t.Errorf("Invalid value for string, expected %s, got %s", expected, got)
+	}
+}
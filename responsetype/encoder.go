@@ -0,0 +1,159 @@
+package responsetype
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// EncoderType is a response type serving classified return values with an Encoder.
+type EncoderType struct {
+	enc Encoder
+}
+
+// encoded is a Result encoded on demand
+type encoded struct {
+	res       *Result
+	enc       Encoder
+	requestID string
+}
+
+func NewEncoderType(enc Encoder) *EncoderType {
+	return &EncoderType{enc: enc}
+}
+
+func (r *EncoderType) Unmarshal(resp interface{}) Response {
+	res := Classify(resp)
+
+	if res == nil {
+		return nil
+	}
+
+	return &encoded{res: res, enc: r.enc}
+}
+
+func (r *EncoderType) DefaultError() Response {
+	return &encoded{
+		res: &Result{Code: http.StatusInternalServerError, Payload: defaultJSONError()},
+		enc: r.enc,
+	}
+}
+
+func (r *EncoderType) GetAcceptedType() string {
+	return r.enc.ContentType()
+}
+
+func (r *EncoderType) String() string {
+	return r.GetAcceptedType()
+}
+
+func (r *encoded) GetCode() int {
+	return r.res.Code
+}
+
+func (r *encoded) GetBody() []byte {
+	_, b := r.encode()
+
+	return b
+}
+
+func (r *encoded) Handle() (int, []byte) {
+	c := r.GetCode()
+	ec, b := r.encode()
+
+	if ec != 0 {
+		c = ec
+	} else if c == 0 && len(b) == 0 {
+		c, b = http.StatusInternalServerError, r.defaultErrorBody()
+	} else if c == 0 {
+		c = http.StatusOK
+	}
+
+	return c, b
+}
+
+func (r *encoded) GetContentType() string {
+	return r.enc.ContentType()
+}
+
+func (r *encoded) GetError() error {
+	return r.res.Err
+}
+
+func (r *encoded) GetHeaders() http.Header {
+	return r.res.Header
+}
+
+// encode marshals the payload, a failure returns the default error and its code
+func (r *encoded) encode() (int, []byte) {
+	if r.res.Payload == nil {
+		return 0, []byte{}
+	}
+
+	b, err := r.enc.Marshal(r.res.Payload)
+
+	if err == nil {
+		return 0, b
+	}
+
+	requestLog(r.requestID).WithField("contenttype", r.enc.ContentType()).WithError(err).Warn("Failed to encode response")
+
+	return http.StatusInternalServerError, r.defaultErrorBody()
+}
+
+func (r *encoded) defaultErrorBody() []byte {
+	b, _ := r.enc.Marshal(defaultJSONError())
+
+	return b
+}
+
+// jsonNative converts json.Marshaler values to plain maps, slices and scalars,
+// so encoders for other formats serve the same document a JSON client receives.
+func jsonNative(v interface{}) (interface{}, error) {
+	jm, ok := v.(json.Marshaler)
+
+	if !ok {
+		return v, nil
+	}
+
+	b, err := jm.MarshalJSON()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var n interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(&n); err != nil {
+		return nil, err
+	}
+
+	return nativeNumbers(n), nil
+}
+
+// nativeNumbers replaces json.Number by int64 or float64, keeping integers compact
+func nativeNumbers(v interface{}) interface{} {
+	switch cv := v.(type) {
+	case json.Number:
+		if i, err := cv.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := cv.Float64()
+
+		return f
+	case map[string]interface{}:
+		for k, e := range cv {
+			cv[k] = nativeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range cv {
+			cv[i] = nativeNumbers(e)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,149 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+	"testing"
+)
+
+type failingEncoder struct{}
+
+func (failingEncoder) ContentType() string { return "test/failing" }
+func (failingEncoder) Marshal(payload interface{}) ([]byte, error) {
+	if _, ok := payload.(JSONError); ok {
+		return []byte("error"), nil
+	}
+
+	return nil, errors.New("marshal failed intentional")
+}
+
+func TestEncoderType_Unmarshal(t *testing.T) {
+	root := NewEncoderType(MsgPack{})
+
+	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(complex(1, 2)), -1, nil)
+	expectResponse(t, root.Unmarshal(int(http.StatusAccepted)), http.StatusAccepted, []byte(""))
+
+	expectEncoded(t, root.Unmarshal("test"), http.StatusOK, MsgPack{}, "test")
+	expectEncoded(t, root.Unmarshal([]int{1, 2}), http.StatusOK, MsgPack{}, []int{1, 2})
+	expectEncoded(t, root.Unmarshal(map[string]float64{"a": 1.5}), http.StatusOK, MsgPack{}, map[string]float64{"a": 1.5})
+	expectEncoded(t, root.Unmarshal(JSONMarshalCoderMock("jsonmarshalcodermock")), 20, MsgPack{}, "jsonmarshalcodermock")
+	expectEncoded(t, root.Unmarshal(JSONMarshalCoderError("jsonmarshalcodererror")), 21, MsgPack{}, "testerror: jsonmarshalcodererror")
+	expectEncoded(t, root.Unmarshal(CoderError(http.StatusConflict)), http.StatusConflict, MsgPack{}, JSONError{Code: http.StatusConflict, Description: "Internal Server Error"})
+	expectEncoded(t, root.Unmarshal(errors.New("testerror")), http.StatusInternalServerError, MsgPack{}, defaultJSONError())
+	expectEncoded(t, root.DefaultError(), http.StatusInternalServerError, MsgPack{}, defaultJSONError())
+
+	res := &Result{Code: http.StatusCreated, Payload: []string{"a"}, Header: http.Header{"X-Test": []string{"a"}}}
+	resp := root.Unmarshal(res)
+
+	expectEncoded(t, resp, http.StatusCreated, MsgPack{}, []interface{}{"a"})
+
+	if h := resp.(Headerer).GetHeaders().Get("X-Test"); h != "a" {
+		t.Errorf("Expected result headers to be kept, got %s", h)
+	}
+
+	ect := "application/msgpack"
+
+	if ct := resp.GetContentType(); ct != ect {
+		t.Errorf("Invalid content type, expected %s, got %s", ect, ct)
+	}
+
+	if at := root.GetAcceptedType(); at != ect {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", ect, at)
+	}
+
+	if str := root.String(); str != ect {
+		t.Errorf("Invalid value for string, expected %s, got %s", ect, str)
+	}
+}
+
+func TestEncoderType_Handle(t *testing.T) {
+	root := NewEncoderType(failingEncoder{})
+
+	c, b := root.Unmarshal([]string{"a"}).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code for failed encoding, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+	if string(b) != "error" {
+		t.Errorf("Invalid body for failed encoding, expected %s, got %s", "error", b)
+	}
+
+	c, b = root.Unmarshal(&Result{}).Handle()
+
+	if c != http.StatusInternalServerError || string(b) != "error" {
+		t.Errorf("Invalid empty response, expected %d %s, got %d %s", http.StatusInternalServerError, "error", c, b)
+	}
+
+	c, b = root.Unmarshal(&Result{Payload: JSONError{}}).Handle()
+
+	if c != http.StatusOK || string(b) != "error" {
+		t.Errorf("Invalid response without code, expected %d %s, got %d %s", http.StatusOK, "error", c, b)
+	}
+}
+
+func TestMsgPack_Marshal(t *testing.T) {
+	b, err := MsgPack{}.Marshal(JSONError{Code: http.StatusNotFound, Description: "x", Err: errors.New("hidden")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) != 2 || decoded["description"] != "x" {
+		t.Errorf("Invalid msgpack body, expected json tags to be used, got %v", decoded)
+	}
+
+	if _, err := (MsgPack{}).Marshal(JSONMarshalMock("\\")); err == nil {
+		t.Error("Expected invalid json from a marshaler to fail")
+	}
+}
+
+func TestCBOR_Marshal(t *testing.T) {
+	b, err := CBOR{}.Marshal(JSONError{Code: http.StatusNotFound, Description: "x", Err: errors.New("hidden")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+
+	if err := cbor.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) != 2 || decoded["description"] != "x" {
+		t.Errorf("Invalid cbor body, expected json tags to be used, got %v", decoded)
+	}
+
+	expected, _ := cborEncMode.Marshal(map[string]interface{}{"a": int64(-3), "b": 0.25})
+	b, _ = CBOR{}.Marshal(map[string]interface{}{"b": 0.25, "a": -3})
+
+	if bytes.Compare(b, expected) != 0 {
+		t.Errorf("Invalid cbor body, expected %v, got %v", expected, b)
+	}
+
+	if ct := (CBOR{}).ContentType(); ct != "application/cbor" {
+		t.Errorf("Invalid content type, expected %s, got %s", "application/cbor", ct)
+	}
+}
+
+func expectEncoded(t *testing.T, r Response, code int, enc Encoder, v interface{}) {
+	t.Helper()
+
+	body, err := enc.Marshal(v)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectResponse(t, r, code, body)
+}
@@ -0,0 +1,392 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"path"
+	"path/filepath"
+	"peterdekok.nl/gotools/logger"
+	"strings"
+	"sync"
+)
+
+// HTML renders a view model with a named html/template.
+//
+// Used as response type, the template set through WithTemplate renders any
+// value which is not recognized otherwise. Errors render the error template
+// with a JSONError as view model, or a minimal built-in page without one.
+type HTML struct {
+	Code      int
+	Template  string
+	Data      interface{}
+	err       error
+	log       logger.Logger
+	templates *HTMLTemplates
+	hdr       http.Header
+	requestID string
+}
+
+type HTMLResponsable interface {
+	ToHTML() *HTML
+}
+
+// HTMLTemplates loads views from a directory, optionally wrapped in a layout.
+// Parsed templates are cached, unless reloading is enabled during development.
+type HTMLTemplates struct {
+	dir           string
+	ext           string
+	layout        string
+	errorTemplate string
+	reload        bool
+	funcs         template.FuncMap
+
+	mu    sync.RWMutex
+	cache map[string]*template.Template
+}
+
+var (
+	defaultHTMLErrorTemplate = template.Must(template.New("error").Funcs(template.FuncMap{"status": CodeToStatus}).Parse(
+		`<!DOCTYPE html><html><head><meta charset="utf-8"><title>{{.Code}} {{status .Code}}</title></head>` +
+			`<body><h1>{{.Code}} {{status .Code}}</h1><p>{{.Description}}</p></body></html>`,
+	))
+
+	ErrInvalidTemplateName = errors.New("invalid template name")
+)
+
+// NewHTMLTemplates loads templates from dir, named by their path relative to dir
+// without the extension (".html" by default), e.g. "users/list".
+func NewHTMLTemplates(dir string) *HTMLTemplates {
+	return &HTMLTemplates{
+		dir:   dir,
+		ext:   ".html",
+		cache: make(map[string]*template.Template),
+	}
+}
+
+// WithExtension sets the file extension of the templates.
+func (t *HTMLTemplates) WithExtension(ext string) *HTMLTemplates {
+	t.ext = ext
+
+	return t
+}
+
+// WithLayout wraps every view in the named layout template.
+// The layout is executed, views define the blocks it uses, e.g. {{define "content"}}.
+func (t *HTMLTemplates) WithLayout(name string) *HTMLTemplates {
+	t.layout = name
+
+	return t
+}
+
+// WithErrorTemplate sets the template rendering error pages, its view model is a JSONError.
+func (t *HTMLTemplates) WithErrorTemplate(name string) *HTMLTemplates {
+	t.errorTemplate = name
+
+	return t
+}
+
+// WithReload parses templates on every render, to pick up changes during development.
+func (t *HTMLTemplates) WithReload(reload bool) *HTMLTemplates {
+	t.reload = reload
+
+	return t
+}
+
+// WithFuncs adds functions available to all templates, already cached templates are discarded.
+func (t *HTMLTemplates) WithFuncs(funcs template.FuncMap) *HTMLTemplates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.funcs == nil {
+		t.funcs = make(template.FuncMap)
+	}
+
+	for k, v := range funcs {
+		t.funcs[k] = v
+	}
+
+	t.cache = make(map[string]*template.Template)
+
+	return t
+}
+
+// Render executes the named template (or its layout) with data.
+func (t *HTMLTemplates) Render(name string, data interface{}) ([]byte, error) {
+	tpl, err := t.lookup(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := tpl.Execute(buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (t *HTMLTemplates) lookup(name string) (*template.Template, error) {
+	if !t.reload {
+		t.mu.RLock()
+		tpl, ok := t.cache[name]
+		t.mu.RUnlock()
+
+		if ok {
+			return tpl, nil
+		}
+	}
+
+	tpl, err := t.parse(name)
+
+	if err != nil || t.reload {
+		return tpl, err
+	}
+
+	t.mu.Lock()
+	t.cache[name] = tpl
+	t.mu.Unlock()
+
+	return tpl, nil
+}
+
+func (t *HTMLTemplates) parse(name string) (*template.Template, error) {
+	view, err := t.path(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{view}
+
+	if len(t.layout) > 0 {
+		layout, err := t.path(t.layout)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The first file names the template which is executed
+		files = []string{layout, view}
+	}
+
+	return template.New(filepath.Base(files[0])).Funcs(t.funcs).ParseFiles(files...)
+}
+
+// path resolves a template name within the directory, refusing to leave it
+func (t *HTMLTemplates) path(name string) (string, error) {
+	clean := path.Clean("/" + name)
+
+	if len(name) == 0 || clean == "/" || strings.Contains(name, "..") || strings.Contains(name, "\\") {
+		return "", ErrInvalidTemplateName
+	}
+
+	return filepath.Join(t.dir, filepath.FromSlash(clean)) + t.ext, nil
+}
+
+func NewHTML(code int, template string, data interface{}) *HTML {
+	return &HTML{
+		Code:     code,
+		Template: template,
+		Data:     data,
+	}
+}
+
+// NewHTMLType creates a response type rendering with the given templates.
+func NewHTMLType(templates *HTMLTemplates) *HTML {
+	return &HTML{templates: templates}
+}
+
+func (r *HTML) WithError(err error) *HTML {
+	r.err = err
+
+	return r
+}
+
+func (r *HTML) WithLogger(log logger.Logger) *HTML {
+	r.log = log
+
+	return r
+}
+
+// WithTemplate sets the template to render, on a response type it is used for plain view models.
+func (r *HTML) WithTemplate(name string) *HTML {
+	r.Template = name
+
+	return r
+}
+
+func (r *HTML) WithTemplates(templates *HTMLTemplates) *HTML {
+	r.templates = templates
+
+	return r
+}
+
+func (r HTML) GetCode() int {
+	return r.Code
+}
+
+func (r HTML) GetBody() []byte {
+	_, b := r.render()
+
+	return b
+}
+
+func (r HTML) Handle() (int, []byte) {
+	c := r.GetCode()
+	ec, b := r.render()
+
+	if ec != 0 {
+		c = ec
+	} else if c == 0 && len(b) == 0 {
+		c = http.StatusInternalServerError
+		b = renderHTMLError(r.templates, defaultJSONError(), r.requestID)
+	} else if c == 0 {
+		c = http.StatusOK
+	}
+
+	if r.err != nil && r.log != nil {
+		r.log.WithFields(logFields(c, r.requestID)).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
+	}
+
+	return c, b
+}
+
+func (r HTML) GetError() error {
+	return r.err
+}
+
+func (r HTML) GetHeaders() http.Header {
+	return r.hdr
+}
+
+func (r HTML) GetContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+func (r *HTML) Unmarshal(resp interface{}) Response {
+	if resp == nil {
+		return &HTML{Code: http.StatusNoContent}
+	}
+
+	if hr, ok := resp.(HTMLResponsable); ok {
+		return r.inherit(hr.ToHTML())
+	}
+
+	switch cResp := resp.(type) {
+	case HTML:
+		return r.inherit(&cResp)
+	case *HTML:
+		return r.inherit(cResp)
+	case template.HTML:
+		return &HTML{Code: http.StatusOK, Data: cResp}
+	}
+
+	code, data := http.StatusOK, resp
+
+	if res := Classify(resp); res != nil {
+		if je, ok := res.ErrorPayload(); ok {
+			return &HTML{Code: res.Code, Data: je, err: res.Err, templates: r.templates, hdr: res.Header}
+		}
+
+		if res.Payload == nil {
+			return &HTML{Code: res.Code, hdr: res.Header}
+		}
+
+		if _, ok := res.Payload.(template.HTML); ok {
+			return &HTML{Code: res.Code, Data: res.Payload, hdr: res.Header}
+		}
+
+		code, data = res.Code, res.Payload
+	}
+
+	// Anything else is a view model for the type's template
+	if len(r.Template) == 0 {
+		return nil
+	}
+
+	return &HTML{Code: code, Template: r.Template, Data: data, templates: r.templates}
+}
+
+func (r *HTML) DefaultError() Response {
+	return &HTML{
+		Code:      http.StatusInternalServerError,
+		Data:      defaultJSONError(),
+		templates: r.templates,
+	}
+}
+
+func (r *HTML) GetAcceptedType() string {
+	return "text/html"
+}
+
+func (r *HTML) String() string {
+	return r.GetAcceptedType()
+}
+
+// inherit copies the response, filling in the type's templates and template name
+func (r *HTML) inherit(h *HTML) *HTML {
+	c := *h
+
+	if c.templates == nil {
+		c.templates = r.templates
+	}
+
+	if len(c.Template) == 0 {
+		c.Template = r.Template
+	}
+
+	return &c
+}
+
+// render returns the body, a failure renders the error page and returns its code.
+func (r HTML) render() (int, []byte) {
+	switch d := r.Data.(type) {
+	case nil:
+		if len(r.Template) == 0 {
+			return 0, []byte{}
+		}
+	case template.HTML:
+		return 0, []byte(d)
+	case JSONError:
+		return 0, renderHTMLError(r.templates, d, r.requestID)
+	}
+
+	if r.templates == nil {
+		requestLog(r.requestID).WithField("template", r.Template).Warn("No html templates to render response")
+
+		return http.StatusInternalServerError, renderHTMLError(nil, defaultJSONError(), r.requestID)
+	}
+
+	b, err := r.templates.Render(r.Template, r.Data)
+
+	if err != nil {
+		requestLog(r.requestID).WithField("template", r.Template).WithError(err).Warn("Failed to render html response")
+
+		return http.StatusInternalServerError, renderHTMLError(r.templates, defaultJSONError(), r.requestID)
+	}
+
+	return 0, b
+}
+
+// renderHTMLError renders the error template, falling back to the built-in page
+func renderHTMLError(t *HTMLTemplates, je JSONError, requestID string) []byte {
+	if t != nil && len(t.errorTemplate) > 0 {
+		b, err := t.Render(t.errorTemplate, je)
+
+		if err == nil {
+			return b
+		}
+
+		requestLog(requestID).WithField("template", t.errorTemplate).WithError(err).Warn("Failed to render html error page")
+	}
+
+	buf := &bytes.Buffer{}
+
+	_ = defaultHTMLErrorTemplate.Execute(buf, je)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,177 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type HTMLResponsableMock struct {
+	code int
+	data interface{}
+}
+
+func (hr HTMLResponsableMock) ToHTML() *HTML {
+	return &HTML{Code: hr.code, Data: hr.data}
+}
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "responsetype-html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestHTMLTemplates_Render(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layout.html":     `<main>{{block "content" .}}{{end}}</main>`,
+		"users/list.html": `{{define "content"}}{{range .}}<p>{{upper .}}</p>{{end}}{{end}}`,
+		"plain.tpl":       `plain {{.}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	tpls := NewHTMLTemplates(dir).WithLayout("layout").WithFuncs(template.FuncMap{"upper": strings.ToUpper})
+
+	b, err := tpls.Render("users/list", []string{"a", "<b>"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("<main><p>A</p><p>&lt;B&gt;</p></main>")
+
+	if bytes.Compare(b, expected) != 0 {
+		t.Errorf("Invalid rendered template, expected %s, got %s", expected, b)
+	}
+
+	if _, ok := tpls.cache["users/list"]; !ok {
+		t.Error("Expected template to be cached")
+	}
+
+	for _, name := range []string{"", "/", "../layout", "users/../../x", "users\\list"} {
+		if _, err := tpls.Render(name, nil); err != ErrInvalidTemplateName {
+			t.Errorf("Expected invalid template name for %q, got %v", name, err)
+		}
+	}
+
+	tpls = NewHTMLTemplates(dir).WithExtension(".tpl").WithReload(true)
+
+	b, _ = tpls.Render("plain", "one")
+
+	if string(b) != "plain one" {
+		t.Errorf("Invalid rendered template, expected %s, got %s", "plain one", b)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "plain.tpl"), []byte("reloaded {{.}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ = tpls.Render("plain", "two")
+
+	if string(b) != "reloaded two" {
+		t.Errorf("Expected template to be reloaded, expected %s, got %s", "reloaded two", b)
+	}
+
+	if len(tpls.cache) != 0 {
+		t.Error("Expected no templates to be cached while reloading")
+	}
+}
+
+func TestHTML_Unmarshal(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"view.html":  `<p>{{.Name}}</p>`,
+		"other.html": `<i>{{.}}</i>`,
+		"error.html": `<h1>{{.Code}}</h1>{{.Description}}`,
+		"bad.html":   `{{.Missing.Field}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	tpls := NewHTMLTemplates(dir)
+	root := NewHTMLType(tpls)
+
+	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(int(http.StatusAccepted)), http.StatusAccepted, []byte(""))
+	expectResponse(t, root.Unmarshal(map[string]string{"Name": "x"}), -1, nil)
+	expectResponse(t, root.Unmarshal(template.HTML("<b>trusted</b>")), http.StatusOK, []byte("<b>trusted</b>"))
+
+	root.WithTemplate("view")
+
+	expectResponse(t, root.Unmarshal(map[string]string{"Name": "<x>"}), http.StatusOK, []byte("<p>&lt;x&gt;</p>"))
+	expectResponse(t, root.Unmarshal(NewHTML(http.StatusCreated, "other", "y")), http.StatusCreated, []byte("<i>y</i>"))
+	expectResponse(t, root.Unmarshal(HTMLResponsableMock{code: http.StatusAccepted, data: map[string]string{"Name": "z"}}), http.StatusAccepted, []byte("<p>z</p>"))
+
+	expectResponse(t, root.Unmarshal(CoderError(http.StatusConflict)), http.StatusConflict, []byte("<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>409 Conflict</title></head><body><h1>409 Conflict</h1><p>Internal Server Error</p></body></html>"))
+
+	tpls.WithErrorTemplate("error")
+
+	expectResponse(t, root.Unmarshal(errors.New("testerror")), http.StatusInternalServerError, []byte("<h1>500</h1>Internal Server Error"))
+	expectResponse(t, root.Unmarshal(JSONError{Code: http.StatusNotFound, Description: "<gone>"}), http.StatusNotFound, []byte("<h1>404</h1>&lt;gone&gt;"))
+	expectResponse(t, root.DefaultError(), http.StatusInternalServerError, []byte("<h1>500</h1>Internal Server Error"))
+
+	c, b := root.Unmarshal(NewHTML(http.StatusOK, "bad", "y")).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code for failed render, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+	if string(b) != "<h1>500</h1>Internal Server Error" {
+		t.Errorf("Invalid body for failed render, expected error page, got %s", b)
+	}
+}
+
+func TestHTML_Handle(t *testing.T) {
+	c, b := (&HTML{}).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code returned, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+	if !bytes.Contains(b, []byte("<h1>500 Internal Server Error</h1>")) {
+		t.Errorf("Invalid body returned, expected default error page, got %s", b)
+	}
+
+	c, _ = NewHTML(http.StatusOK, "view", nil).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code without templates, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+}
+
+func TestHTML_GetContentType(t *testing.T) {
+	expected := "text/html; charset=utf-8"
+
+	if got := (&HTML{}).GetContentType(); got != expected {
+		t.Errorf("Invalid HTML content type, expected %s, got %s", expected, got)
+	}
+
+	expected = "text/html"
+
+	if got := (&HTML{}).GetAcceptedType(); got != expected {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", expected, got)
+	}
+
+	if got := (&HTML{}).String(); got != expected {
+		t.Errorf("Invalid value for string, expected %s, got %s", expected, got)
+	}
+}
@@ -3,17 +3,45 @@ package responsetype
 import (
 	"bytes"
 	"encoding/json"
-	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 	"peterdekok.nl/gotools/logger"
-	"reflect"
 )
 
 type JSON struct {
-	Code int
-	Body interface{}
-	err  error
-	log  logger.Logger
+	Code      int
+	Body      interface{}
+	err       error
+	log       logger.Logger
+	opts      *JSONOptions
+	hdr       http.Header
+	requestID string
+}
+
+// JSONEncoder is the subset of *json.Encoder used to encode bodies.
+// Alternative implementations (e.g. jsoniter or goccy/go-json) satisfy it as well.
+type JSONEncoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// JSONOptions configure the encoding of JSON bodies.
+// The zero value encodes exactly like encoding/json.Marshal.
+type JSONOptions struct {
+	// Prefix and Indent are passed to the encoder's SetIndent when either is set.
+	Prefix string
+	Indent string
+
+	// PrettyQuery enables indentation for requests carrying a `pretty` query parameter.
+	// Indent defaults to two spaces when it is not set.
+	PrettyQuery bool
+
+	// DisableHTMLEscape stops the encoder from escaping <, > and & in strings.
+	DisableHTMLEscape bool
+
+	// NewEncoder creates the encoder used for the body, defaults to json.NewEncoder.
+	NewEncoder func(w io.Writer) JSONEncoder
 }
 
 type JSONResponsable interface {
@@ -50,10 +78,37 @@ func (r *JSON) WithLogger(log logger.Logger) *JSON {
 	return r
 }
 
+// WithHeader adds a header to the response.
+func (r *JSON) WithHeader(key, value string) *JSON {
+	if r.hdr == nil {
+		r.hdr = make(http.Header)
+	}
+
+	r.hdr.Add(key, value)
+
+	return r
+}
+
+// WithOptions sets the encoding options.
+// Used on a response type, the options are inherited by all responses it unmarshals.
+func (r *JSON) WithOptions(opts JSONOptions) *JSON {
+	r.opts = &opts
+
+	return r
+}
+
 func (r JSON) GetCode() int {
 	return r.Code
 }
 
+func (r JSON) GetError() error {
+	return r.err
+}
+
+func (r JSON) GetHeaders() http.Header {
+	return r.hdr
+}
+
 func (r JSON) GetBody() []byte {
 	if r.Body == nil {
 		return []byte{}
@@ -66,11 +121,11 @@ func (r JSON) GetBody() []byte {
 		return []byte(b)
 	}
 
-	if b, err := json.Marshal(r); err == nil {
+	if b, err := r.opts.marshal(r.Body, true); err == nil {
 		return b
 	}
 
-	log.Warn("Failed to marshal json response")
+	requestLog(r.requestID).Warn("Failed to marshal json response")
 
 	return InternalServerErrorJsonBytes
 }
@@ -89,17 +144,14 @@ func (r JSON) Handle() (int, []byte) {
 	}
 
 	if r.err != nil && r.log != nil {
-		r.log.WithFields(logrus.Fields{
-			"code":   c,
-			"status": CodeToStatus(c),
-		}).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
+		r.log.WithFields(logFields(c, r.requestID)).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
 	}
 
 	return c, b
 }
 
 func (r JSON) MarshalJSON() ([]byte, error) {
-	return json.Marshal(r.Body)
+	return r.opts.marshal(r.Body, false)
 }
 
 func (r JSON) GetContentType() string {
@@ -107,86 +159,54 @@ func (r JSON) GetContentType() string {
 }
 
 func (r *JSON) Unmarshal(resp interface{}) Response {
-	if resp == nil {
-		return &JSON{Code: http.StatusNoContent, Body: []byte{}}
+	cResp := r.unmarshal(resp)
+
+	// Copy before inheriting the options, the response might be shared between requests
+	if j, ok := cResp.(*JSON); ok && j.opts == nil && r.opts != nil {
+		c := *j
+		c.opts = r.opts
+
+		return &c
+	}
+
+	return cResp
+}
+
+// BindRequest enables pretty printing for the request if allowed by the options.
+func (r *JSON) BindRequest(req *http.Request) ResponseType {
+	if r.opts == nil || !r.opts.PrettyQuery || !isPrettyRequest(req) {
+		return r
+	}
+
+	opts := *r.opts
+	opts.PrettyQuery = false
+
+	if len(opts.Indent) == 0 {
+		opts.Indent = "  "
 	}
 
+	return &JSON{opts: &opts}
+}
+
+func (r *JSON) unmarshal(resp interface{}) Response {
 	if jr, ok := resp.(JSONResponsable); ok {
 		return jr.ToJSON()
 	}
 
 	switch cResp := resp.(type) {
-	case string:
-		if len(cResp) == 0 {
-			return &JSON{Code: http.StatusNoContent, Body: []byte{}}
-		}
-
-		return &JSON{Code: http.StatusOK, Body: cResp}
 	case JSON:
 		return &cResp
 	case *JSON:
 		return cResp
-	case JSONError:
-		return &JSON{Code: cResp.Code, Body: cResp, err:  cResp.Err}
-	case *JSONError:
-		return &JSON{Code: cResp.Code, Body: cResp, err:  cResp.Err}
-	case int:
-		return &JSON{Code: cResp}
-	case int32:
-		return &JSON{Code: int(cResp)}
-	case int16:
-		return &JSON{Code: int(cResp)}
-	case int8:
-		return &JSON{Code: int(cResp)}
-	case uint16:
-		return &JSON{Code: int(cResp)}
-	case uint8:
-		return &JSON{Code: int(cResp)}
-	}
-
-	// We assume an error adhering to the json.Marshaler interface
-	// will be consumable for public r
-	if err, ok := resp.(error); ok {
-		j := r.defaultError()
-
-		j.err = err
-
-		mar, marOk := resp.(json.Marshaler)
-		cod, codOk := resp.(Coder)
-
-		if codOk {
-			j.Code = cod.GetCode()
-		}
-
-		if marOk {
-			j.Body = mar
-		} else if codOk {
-			je := r.defaultJsonError()
-			je.Code = j.Code
-			je.Err = err
-
-			j.Body = je
-		}
-
-		return j
-	} else if jm, ok := resp.(json.Marshaler); ok {
-		c := http.StatusOK
-
-		if coder, ok := resp.(Coder); ok {
-			c = coder.GetCode()
-		}
-
-		if b, err := json.Marshal(jm); err == nil {
-			return &JSON{Code: c, Body: b}
-		}
 	}
 
-	switch reflect.TypeOf(resp).Kind() {
-	case reflect.Map, reflect.Slice, reflect.Array:
-		return &JSON{Code: http.StatusOK, Body: resp}
+	res := Classify(resp)
+
+	if res == nil {
+		return nil
 	}
 
-	return nil
+	return &JSON{Code: res.Code, Body: res.Payload, err: res.Err, hdr: res.Header}
 }
 
 func (r *JSON) DefaultError() Response {
@@ -197,14 +217,12 @@ func (r *JSON) defaultError() *JSON {
 	return &JSON{
 		Code: http.StatusInternalServerError,
 		Body: r.defaultJsonError(),
+		opts: r.opts,
 	}
 }
 
 func (r *JSON) defaultJsonError() JSONError {
-	return JSONError{
-		Code:        http.StatusInternalServerError,
-		Description: http.StatusText(http.StatusInternalServerError),
-	}
+	return defaultJSONError()
 }
 
 func (r *JSON) GetAcceptedType() string {
@@ -219,6 +237,7 @@ func (r *JSON) String() string {
 type JSONError struct {
 	Code        int         `json:"code"`
 	Description interface{} `json:"description"`
+	RequestID   string      `json:"request_id,omitempty"`
 	Err         error       `json:"-"`
 }
 
@@ -241,3 +260,60 @@ func NewJSONError(code int, description interface{}, err error) *JSON {
 func (je JSONError) GetCode() int {
 	return je.Code
 }
+
+func defaultJSONError() JSONError {
+	return JSONError{
+		Code:        http.StatusInternalServerError,
+		Description: http.StatusText(http.StatusInternalServerError),
+	}
+}
+
+// marshal encodes v according to the options, nil options fall back to json.Marshal.
+// Indentation is only applied when indent is set, MarshalJSON output has to stay compact.
+func (o *JSONOptions) marshal(v interface{}, indent bool) ([]byte, error) {
+	if o == nil {
+		return json.Marshal(v)
+	}
+
+	buf := &bytes.Buffer{}
+
+	var enc JSONEncoder
+
+	if o.NewEncoder != nil {
+		enc = o.NewEncoder(buf)
+	} else {
+		enc = json.NewEncoder(buf)
+	}
+
+	enc.SetEscapeHTML(!o.DisableHTMLEscape)
+
+	if indent && (len(o.Prefix) > 0 || len(o.Indent) > 0) {
+		enc.SetIndent(o.Prefix, o.Indent)
+	}
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Encoders terminate each value with a newline, json.Marshal does not
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func isPrettyRequest(req *http.Request) bool {
+	if req == nil || req.URL == nil {
+		return false
+	}
+
+	v, ok := req.URL.Query()["pretty"]
+
+	if !ok {
+		return false
+	}
+
+	switch v[0] {
+	case "0", "false":
+		return false
+	}
+
+	return true
+}
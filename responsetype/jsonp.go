@@ -0,0 +1,190 @@
+package responsetype
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+// JSONP serves JSON responses wrapped in a javascript callback, for legacy clients.
+// The callback is taken from the request's query, requests without one are served plain JSON.
+// Handler return values are unmarshalled by the JSON rules.
+type JSONP struct {
+	// CallbackParam is the query parameter holding the callback name, defaults to "callback".
+	CallbackParam string
+
+	json     *JSON
+	callback string
+	invalid  bool
+}
+
+type jsonpResponse struct {
+	Response
+
+	callback string
+}
+
+const maxJSONPCallbackLength = 128
+
+var jsonpCallbackRegexp = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
+// WithJSON sets the JSON response type used to unmarshal and encode the padded body.
+func (r *JSONP) WithJSON(jt *JSON) *JSONP {
+	r.json = jt
+
+	return r
+}
+
+// BindRequest reads and validates the callback from the request.
+func (r *JSONP) BindRequest(req *http.Request) ResponseType {
+	var jt ResponseType = r.jsonType()
+
+	if rb, ok := jt.(RequestBinder); ok {
+		jt = rb.BindRequest(req)
+	}
+
+	if req == nil || req.URL == nil {
+		return jt
+	}
+
+	cb, ok := req.URL.Query()[r.callbackParam()]
+
+	if !ok {
+		return jt
+	}
+
+	bound := &JSONP{
+		CallbackParam: r.CallbackParam,
+		callback:      cb[0],
+		invalid:       !IsValidJSONPCallback(cb[0]),
+	}
+
+	if j, ok := jt.(*JSON); ok {
+		bound.json = j
+	}
+
+	return bound
+}
+
+func (r *JSONP) Unmarshal(resp interface{}) Response {
+	if r.invalid || len(r.callback) == 0 {
+		return r.invalidCallback()
+	}
+
+	cResp := r.jsonType().Unmarshal(resp)
+
+	if cResp == nil {
+		return nil
+	}
+
+	return &jsonpResponse{Response: cResp, callback: r.callback}
+}
+
+func (r *JSONP) DefaultError() Response {
+	if r.invalid || len(r.callback) == 0 {
+		return r.jsonType().DefaultError()
+	}
+
+	return &jsonpResponse{Response: r.jsonType().DefaultError(), callback: r.callback}
+}
+
+func (r *JSONP) GetAcceptedType() string {
+	return "application/javascript"
+}
+
+func (r *JSONP) String() string {
+	return r.GetAcceptedType()
+}
+
+func (r *JSONP) jsonType() *JSON {
+	if r.json == nil {
+		return &JSON{}
+	}
+
+	return r.json
+}
+
+func (r *JSONP) callbackParam() string {
+	if len(r.CallbackParam) == 0 {
+		return "callback"
+	}
+
+	return r.CallbackParam
+}
+
+func (r *JSONP) invalidCallback() Response {
+	j := NewJSONError(http.StatusBadRequest, "Invalid JSONP callback", nil).
+		WithHeader("X-Content-Type-Options", "nosniff")
+
+	j.opts = r.jsonType().opts
+
+	return j
+}
+
+// IsValidJSONPCallback reports whether the callback is a (dotted) javascript identifier,
+// anything else could be used to inject script into the response.
+func IsValidJSONPCallback(cb string) bool {
+	return len(cb) > 0 && len(cb) <= maxJSONPCallbackLength && jsonpCallbackRegexp.MatchString(cb)
+}
+
+// Handle pads the JSON body, the callback is invoked even without content.
+func (r *jsonpResponse) Handle() (int, []byte) {
+	c, b := r.Response.Handle()
+
+	if c == http.StatusNoContent {
+		c = http.StatusOK
+	}
+
+	return c, r.pad(b)
+}
+
+func (r *jsonpResponse) GetBody() []byte {
+	return r.pad(r.Response.GetBody())
+}
+
+func (r *jsonpResponse) GetContentType() string {
+	return "application/javascript"
+}
+
+func (r *jsonpResponse) GetError() error {
+	if e, ok := r.Response.(Errorer); ok {
+		return e.GetError()
+	}
+
+	return nil
+}
+
+func (r *jsonpResponse) GetHeaders() http.Header {
+	h := make(http.Header)
+
+	if hr, ok := r.Response.(Headerer); ok {
+		for k, v := range hr.GetHeaders() {
+			h[k] = v
+		}
+	}
+
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	return h
+}
+
+// pad wraps the body in the callback, the leading comment guards against
+// content sniffing attacks (e.g. Rosetta Flash) on the callback name.
+// A body which is not valid JSON, e.g. a string returned by the handler, is passed
+// as string literal, it would be executed as script otherwise.
+func (r *jsonpResponse) pad(b []byte) []byte {
+	if len(b) == 0 {
+		b = []byte("null")
+	} else if !json.Valid(b) {
+		b, _ = json.Marshal(string(b))
+	}
+
+	buf := bytes.NewBufferString("/**/")
+	buf.WriteString(r.callback)
+	buf.WriteByte('(')
+	buf.Write(b)
+	buf.WriteString(");")
+
+	return buf.Bytes()
+}
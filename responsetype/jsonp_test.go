@@ -0,0 +1,187 @@
+package responsetype
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestIsValidJSONPCallback(t *testing.T) {
+	expected := map[string]bool{
+		"":                        false,
+		"cb":                      true,
+		"$":                       true,
+		"_cb1":                    true,
+		"jQuery.fn.cb_123":        true,
+		"1cb":                     false,
+		"cb.":                     false,
+		"cb()":                    false,
+		"alert(1);cb":             false,
+		"cb[0]":                   false,
+		"cb\n":                    false,
+		"<script>":                false,
+		string(make([]byte, 129)): false,
+	}
+
+	for cb, valid := range expected {
+		got := IsValidJSONPCallback(cb)
+
+		if got != valid {
+			t.Errorf("Invalid callback validation for %q, expected %t, got %t", cb, valid, got)
+		}
+	}
+}
+
+func TestJSONP_BindRequest(t *testing.T) {
+	root := &JSONP{}
+
+	if _, ok := root.BindRequest(&http.Request{URL: &url.URL{}}).(*JSON); !ok {
+		t.Error("Expected request without callback to be served as JSON")
+	}
+
+	bound, ok := root.BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=cb"}}).(*JSONP)
+
+	if !ok {
+		t.Fatal("Expected request with callback to be served as JSONP")
+	}
+
+	if bound.callback != "cb" || bound.invalid {
+		t.Errorf("Invalid bound callback, expected %s, got %s (invalid: %t)", "cb", bound.callback, bound.invalid)
+	}
+
+	root.CallbackParam = "jsonp"
+
+	if _, ok := root.BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=cb"}}).(*JSON); !ok {
+		t.Error("Expected custom callback parameter to be used")
+	}
+
+	bound = root.BindRequest(&http.Request{URL: &url.URL{RawQuery: "jsonp=alert(1)"}}).(*JSONP)
+
+	if !bound.invalid {
+		t.Error("Expected unsafe callback to be marked invalid")
+	}
+}
+
+func TestJSONP_Unmarshal(t *testing.T) {
+	root := (&JSONP{}).BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=cb"}})
+
+	resp := root.Unmarshal([]string{"a"})
+
+	ec := http.StatusOK
+	eb := []byte("/**/cb([\"a\"]);")
+	c, b := resp.Handle()
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+
+	ect := "application/javascript"
+	ct := resp.GetContentType()
+
+	if ct != ect {
+		t.Errorf("Invalid content type, expected %s, got %s", ect, ct)
+	}
+
+	eh := "nosniff"
+	h := resp.(Headerer).GetHeaders().Get("X-Content-Type-Options")
+
+	if h != eh {
+		t.Errorf("Invalid nosniff header, expected %s, got %s", eh, h)
+	}
+
+	ec = http.StatusOK
+	eb = []byte("/**/cb(null);")
+	c, b = root.Unmarshal(nil).Handle()
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+
+	ec = http.StatusNotFound
+	eb = []byte("/**/cb({\"code\":404,\"description\":\"Not Found\"});")
+	c, b = root.Unmarshal(NewJSONError(http.StatusNotFound, nil, nil)).Handle()
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+
+	if root.Unmarshal(complex(1, 2)) != nil {
+		t.Error("Expected unrecognized response to be nil")
+	}
+
+	eb = append(append([]byte("/**/cb("), InternalServerErrorJsonBytes...), ");"...)
+	b = root.DefaultError().GetBody()
+
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid default error body, expected %s, got %s", eb, b)
+	}
+}
+
+func TestJSONP_Unmarshal_Script(t *testing.T) {
+	root := (&JSONP{}).BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=cb"}})
+
+	expected := map[interface{}]string{
+		"1);alert(document.cookie);//":        `/**/cb("1);alert(document.cookie);//");`,
+		"</script><script>alert(1)</script>":  `/**/cb("\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e");`,
+		`{"pre":"encoded"}`:                   `/**/cb({"pre":"encoded"});`,
+		NewJSON(http.StatusOK, []byte("x()")): `/**/cb("x()");`,
+	}
+
+	for resp, eb := range expected {
+		if _, b := root.Unmarshal(resp).Handle(); string(b) != eb {
+			t.Errorf("Invalid body for %v, expected %s, got %s", resp, eb, b)
+		}
+	}
+}
+
+func TestJSONP_Unmarshal_InvalidCallback(t *testing.T) {
+	root := (&JSONP{}).BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=alert(1)"}})
+
+	resp := root.Unmarshal([]string{"a"})
+
+	ec := http.StatusBadRequest
+	eb := []byte("{\"code\":400,\"description\":\"Invalid JSONP callback\"}")
+	c, b := resp.Handle()
+
+	if c != ec {
+		t.Errorf("Invalid code returned, expected %d, got %d", ec, c)
+	}
+	if bytes.Compare(b, eb) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", eb, b)
+	}
+
+	ect := "application/json"
+	ct := resp.GetContentType()
+
+	if ct != ect {
+		t.Errorf("Invalid content type, expected %s, got %s", ect, ct)
+	}
+
+	if bytes.Compare(root.DefaultError().GetBody(), InternalServerErrorJsonBytes) != 0 {
+		t.Error("Expected default error not to be padded with an invalid callback")
+	}
+}
+
+func TestJSONP_GetAcceptedType(t *testing.T) {
+	at := (&JSONP{}).GetAcceptedType()
+
+	expected := "application/javascript"
+
+	if at != expected {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", expected, at)
+	}
+
+	if str := (&JSONP{}).String(); str != expected {
+		t.Errorf("Invalid value for string, expected %s, got %s", expected, str)
+	}
+}
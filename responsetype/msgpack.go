@@ -0,0 +1,36 @@
+package responsetype
+
+import (
+	"bytes"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgPack encodes payloads as MessagePack.
+// Struct fields are named by their json tags and json.Marshaler values are encoded
+// as the document they marshal to, so clients receive the same structure as JSON.
+type MsgPack struct{}
+
+func (MsgPack) ContentType() string {
+	return "application/msgpack"
+}
+
+// Marshal sorts map keys, for output identical between requests
+func (MsgPack) Marshal(payload interface{}) ([]byte, error) {
+	v, err := jsonNative(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	enc := msgpack.NewEncoder(buf)
+	enc.SetSortMapKeys(true)
+	enc.SetCustomStructTag("json")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
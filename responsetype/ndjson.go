@@ -0,0 +1,362 @@
+package responsetype
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"peterdekok.nl/gotools/logger"
+	"reflect"
+)
+
+// NDJSON serves sequences as newline delimited JSON (JSON Lines), one document per element.
+// Slices, arrays, channels and iterators (func(yield func(T) bool)) are streamed as they
+// are produced, any other value is unmarshalled by the JSON rules and served as a single line.
+// An error element ends the stream with a terminal line: {"error":{"code":...,"description":...}}
+//
+// The stream stops when the request is done or a write fails, producers sending on a channel
+// must select on the request context to not block forever. A nil channel is an empty stream.
+type NDJSON struct {
+	Code      int
+	Body      interface{}
+	err       error
+	log       logger.Logger
+	opts      *JSONOptions
+	hdr       http.Header
+	requestID string
+
+	// doc is the JSON response of a single document
+	doc Response
+}
+
+type ndjsonErrorLine struct {
+	Error JSONError `json:"error"`
+}
+
+var (
+	ndjsonLineSeparator = []byte("\n")
+)
+
+func NewNDJSON(code int, body interface{}) *NDJSON {
+	return &NDJSON{
+		Code: code,
+		Body: body,
+	}
+}
+
+func (r *NDJSON) WithError(err error) *NDJSON {
+	r.err = err
+
+	return r
+}
+
+func (r *NDJSON) WithLogger(log logger.Logger) *NDJSON {
+	r.log = log
+
+	return r
+}
+
+// WithOptions sets the encoding options for each line, indentation is never applied.
+func (r *NDJSON) WithOptions(opts JSONOptions) *NDJSON {
+	r.opts = &opts
+
+	return r
+}
+
+func (r NDJSON) GetCode() int {
+	return r.Code
+}
+
+// GetBody buffers the complete stream.
+func (r NDJSON) GetBody() []byte {
+	buf := &bytes.Buffer{}
+
+	// A failing stream has already written its terminal error line
+	_ = r.Stream(buf)
+
+	return buf.Bytes()
+}
+
+func (r NDJSON) Handle() (int, []byte) {
+	c := r.GetCode()
+	b := r.GetBody()
+
+	if c == 0 && len(b) == 0 {
+		c = http.StatusInternalServerError
+		b = InternalServerErrorJsonBytes
+	} else if c == 0 {
+		c = http.StatusOK
+	}
+
+	if r.err != nil && r.log != nil {
+		r.log.WithFields(logFields(c, r.requestID)).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
+	}
+
+	return c, b
+}
+
+// Stream writes the body line by line, the writer is expected to flush each write.
+func (r NDJSON) Stream(w io.Writer) error {
+	return r.StreamContext(context.Background(), w)
+}
+
+// StreamContext writes the body line by line until the context is done.
+func (r NDJSON) StreamContext(ctx context.Context, w io.Writer) error {
+	switch b := r.Body.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return r.writeRaw(w, b)
+	case json.RawMessage:
+		return r.writeRaw(w, b)
+	case string:
+		return r.writeRaw(w, []byte(b))
+	}
+
+	if !isSequence(r.Body) {
+		return r.writeLine(w, r.Body)
+	}
+
+	var err error
+
+	ctxErr := eachInSequence(ctx, r.Body, func(v interface{}) bool {
+		err = r.writeLine(w, v)
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctxErr
+}
+
+func (r NDJSON) GetError() error {
+	return r.err
+}
+
+func (r NDJSON) GetHeaders() http.Header {
+	return r.hdr
+}
+
+func (r NDJSON) GetContentType() string {
+	return "application/x-ndjson"
+}
+
+func (r *NDJSON) Unmarshal(resp interface{}) Response {
+	if resp == nil {
+		return &NDJSON{Code: http.StatusNoContent, opts: r.opts}
+	}
+
+	switch cResp := resp.(type) {
+	case NDJSON:
+		return &cResp
+	case *NDJSON:
+		return cResp
+	}
+
+	if isSequence(resp) {
+		return &NDJSON{Code: http.StatusOK, Body: resp, opts: r.opts}
+	}
+
+	if res, ok := resultOf(resp); ok && isSequence(res.Payload) {
+		return &NDJSON{Code: res.Code, Body: res.Payload, err: res.Err, opts: r.opts, hdr: res.Header}
+	}
+
+	// Anything else is served as a single document
+	jResp := (&JSON{opts: r.opts}).Unmarshal(resp)
+
+	if jResp == nil {
+		return nil
+	}
+
+	c, b := jResp.Handle()
+
+	nd := &NDJSON{Code: c, Body: json.RawMessage(b), opts: r.opts, doc: jResp}
+
+	if h, ok := jResp.(Headerer); ok {
+		nd.hdr = h.GetHeaders()
+	}
+
+	if e, ok := jResp.(Errorer); ok {
+		nd.err = e.GetError()
+	}
+
+	return nd
+}
+
+func (r *NDJSON) DefaultError() Response {
+	return &NDJSON{
+		Code: http.StatusInternalServerError,
+		Body: json.RawMessage(InternalServerErrorJsonBytes),
+		opts: r.opts,
+	}
+}
+
+func (r *NDJSON) GetAcceptedType() string {
+	return "application/x-ndjson"
+}
+
+func (r *NDJSON) String() string {
+	return r.GetAcceptedType()
+}
+
+func (r NDJSON) writeRaw(w io.Writer, b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	line := make([]byte, 0, len(b)+len(ndjsonLineSeparator))
+	line = append(append(line, b...), ndjsonLineSeparator...)
+
+	_, err := w.Write(line)
+
+	return err
+}
+
+// writeLine encodes a single element, errors (returned or encountered) end the stream
+func (r NDJSON) writeLine(w io.Writer, v interface{}) error {
+	var line ndjsonErrorLine
+
+	switch e := v.(type) {
+	case JSONError:
+		line.Error = e
+	case *JSONError:
+		line.Error = *e
+	case error:
+		line.Error = errorToJSONError(e)
+	default:
+		b, err := r.opts.marshal(v, false)
+
+		if err == nil {
+			return r.writeRaw(w, b)
+		}
+
+		line.Error = errorToJSONError(err)
+	}
+
+	if len(line.Error.RequestID) == 0 {
+		line.Error.RequestID = r.requestID
+	}
+
+	b, err := r.opts.marshal(line, false)
+
+	if err != nil {
+		return err
+	}
+
+	if err := r.writeRaw(w, b); err != nil {
+		return err
+	}
+
+	if line.Error.Err != nil {
+		return line.Error.Err
+	}
+
+	return fmt.Errorf("stream terminated with error: %d %v", line.Error.Code, line.Error.Description)
+}
+
+func errorToJSONError(err error) JSONError {
+	code := http.StatusInternalServerError
+
+	if cod, ok := err.(Coder); ok {
+		code = cod.GetCode()
+	}
+
+	return JSONError{
+		Code:        code,
+		Description: CodeToStatus(code),
+		Err:         err,
+	}
+}
+
+var iteratorYieldType = reflect.TypeOf(true)
+
+// isSequence reports whether the value is a slice, array, channel or iterator.
+// Byte slices are considered to be a single (encoded) document.
+func isSequence(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+
+	t := reflect.TypeOf(v)
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		return true
+	case reflect.Chan:
+		return t.ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		return isIterator(t)
+	}
+
+	return false
+}
+
+// isIterator matches the shape of iter.Seq: func(yield func(T) bool)
+func isIterator(t reflect.Type) bool {
+	if t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+
+	y := t.In(0)
+
+	return y.Kind() == reflect.Func && y.NumIn() == 1 && y.NumOut() == 1 && y.Out(0) == iteratorYieldType
+}
+
+// eachInSequence calls fn for every element until fn returns false,
+// returning the error of the context when it is done first
+func eachInSequence(ctx context.Context, seq interface{}, fn func(v interface{}) bool) error {
+	rv := reflect.ValueOf(seq)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if !fn(rv.Index(i).Interface()) {
+				return nil
+			}
+		}
+	case reflect.Chan:
+		if rv.IsNil() {
+			return nil
+		}
+
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: rv},
+		}
+
+		for {
+			i, v, ok := reflect.Select(cases)
+
+			if i == 0 {
+				return ctx.Err()
+			}
+
+			if !ok || !fn(v.Interface()) {
+				return nil
+			}
+		}
+	case reflect.Func:
+		if rv.IsNil() {
+			return nil
+		}
+
+		yield := reflect.MakeFunc(rv.Type().In(0), func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(ctx.Err() == nil && fn(args[0].Interface()))}
+		})
+
+		rv.Call([]reflect.Value{yield})
+	}
+
+	return ctx.Err()
+}
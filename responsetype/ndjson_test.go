@@ -0,0 +1,178 @@
+package responsetype
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type failingWriter struct {
+	writes int
+}
+
+func (fw *failingWriter) Write(b []byte) (int, error) {
+	fw.writes++
+
+	return 0, errors.New("write failed intentional")
+}
+
+func TestNDJSON_Unmarshal(t *testing.T) {
+	root := &NDJSON{}
+
+	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(complex(1, 2)), -1, nil)
+	expectResponse(t, root.Unmarshal(int(http.StatusAccepted)), http.StatusAccepted, []byte(""))
+
+	expectResponse(t, root.Unmarshal([]string{"a", "b"}), http.StatusOK, []byte("\"a\"\n\"b\"\n"))
+	expectResponse(t, root.Unmarshal([2]int{1, 2}), http.StatusOK, []byte("1\n2\n"))
+	expectResponse(t, root.Unmarshal([]map[string]int{{"a": 1}, {"b": 2}}), http.StatusOK, []byte("{\"a\":1}\n{\"b\":2}\n"))
+	expectResponse(t, root.Unmarshal(map[string]int{"a": 1}), http.StatusOK, []byte("{\"a\":1}\n"))
+
+	expectResponse(t, root.Unmarshal(NewJSONError(http.StatusNotFound, nil, nil)), http.StatusNotFound, []byte("{\"code\":404,\"description\":\"Not Found\"}\n"))
+	expectResponse(t, root.Unmarshal(errors.New("testerror")), http.StatusInternalServerError, append(InternalServerErrorJsonBytes, '\n'))
+
+	err := errors.New("testerror")
+
+	if e := root.Unmarshal(err).(Errorer).GetError(); e != err {
+		t.Errorf("Invalid single document error, expected %v, got %v", err, e)
+	}
+
+	ch := make(chan interface{}, 3)
+	ch <- "a"
+	ch <- CoderError(http.StatusConflict)
+	ch <- "unreachable"
+	close(ch)
+
+	expectResponse(t, root.Unmarshal(ch), http.StatusOK, []byte("\"a\"\n{\"error\":{\"code\":409,\"description\":\"Conflict\"}}\n"))
+
+	seq := func(yield func(int) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	expectResponse(t, root.Unmarshal(seq), http.StatusOK, []byte("1\n2\n3\n"))
+	expectResponse(t, root.Unmarshal([]interface{}{1, make(chan int), 3}), http.StatusOK, []byte("1\n{\"error\":{\"code\":500,\"description\":\"Internal Server Error\"}}\n"))
+
+	nd := NewNDJSON(http.StatusCreated, []string{"a"})
+	expectResponse(t, root.Unmarshal(nd), http.StatusCreated, []byte("\"a\"\n"))
+	expectResponse(t, root.Unmarshal(*nd), http.StatusCreated, []byte("\"a\"\n"))
+}
+
+func TestNDJSON_Stream(t *testing.T) {
+	seq := func(yield func(string) bool) {
+		for _, s := range []string{"a", "b", "c"} {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+
+	fw := &failingWriter{}
+
+	if err := NewNDJSON(http.StatusOK, seq).Stream(fw); err == nil {
+		t.Error("Expected write error to be returned")
+	}
+
+	if fw.writes != 1 {
+		t.Errorf("Expected stream to stop after failed write, expected %d writes, got %d", 1, fw.writes)
+	}
+
+	buf := &bytes.Buffer{}
+	err := NewNDJSON(http.StatusOK, []interface{}{"a", errors.New("testerror"), "b"}).Stream(buf)
+
+	if err == nil || err.Error() != "testerror" {
+		t.Errorf("Expected stream error to be returned, got %v", err)
+	}
+
+	expected := []byte("\"a\"\n{\"error\":{\"code\":500,\"description\":\"Internal Server Error\"}}\n")
+
+	if bytes.Compare(buf.Bytes(), expected) != 0 {
+		t.Errorf("Invalid streamed body, expected %s, got %s", expected, buf.Bytes())
+	}
+}
+
+func TestNDJSON_StreamContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	ch := make(chan int)
+
+	go func() {
+		defer close(done)
+
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	fw := &failingWriter{}
+
+	if err := NewNDJSON(http.StatusOK, ch).StreamContext(ctx, fw); err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("Expected write error to be returned, got %v", err)
+	}
+
+	if fw.writes != 1 {
+		t.Errorf("Expected stream to stop after failed write, expected %d writes, got %d", 1, fw.writes)
+	}
+
+	cancel()
+	<-done
+
+	if err := NewNDJSON(http.StatusOK, make(chan int)).StreamContext(ctx, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the stream of a blocked channel to end with the context, got %v", err)
+	}
+
+	var nilCh chan int
+
+	if err := NewNDJSON(http.StatusOK, nilCh).Stream(&bytes.Buffer{}); err != nil {
+		t.Errorf("Expected a nil channel to be an empty stream, got %v", err)
+	}
+}
+
+func TestNDJSON_Handle(t *testing.T) {
+	c, b := (&NDJSON{}).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code returned, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+	if bytes.Compare(b, InternalServerErrorJsonBytes) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", InternalServerErrorJsonBytes, b)
+	}
+
+	c, b = (&NDJSON{Body: []int{1}}).WithOptions(JSONOptions{Indent: "  "}).Handle()
+
+	if c != http.StatusOK {
+		t.Errorf("Invalid code returned, expected %d, got %d", http.StatusOK, c)
+	}
+	if bytes.Compare(b, []byte("1\n")) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", "1\n", b)
+	}
+}
+
+func TestNDJSON_DefaultError(t *testing.T) {
+	expectResponse(t, (&NDJSON{}).DefaultError(), http.StatusInternalServerError, append(InternalServerErrorJsonBytes, '\n'))
+}
+
+func TestNDJSON_GetContentType(t *testing.T) {
+	expected := "application/x-ndjson"
+
+	if got := (&NDJSON{}).GetContentType(); got != expected {
+		t.Errorf("Invalid NDJSON content type, expected %s, got %s", expected, got)
+	}
+
+	if got := (&NDJSON{}).GetAcceptedType(); got != expected {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", expected, got)
+	}
+
+	if got := (&NDJSON{}).String(); got != expected {
+		t.Errorf("Invalid value for string, expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,265 @@
+package responsetype
+
+import (
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"peterdekok.nl/gotools/logger"
+)
+
+// Protobuf serves proto.Message values in the binary wire format.
+// Errors are served as google.rpc.Status messages, as gRPC-gateway clients expect,
+// with the status code mapped to the nearest gRPC code.
+type Protobuf struct {
+	Code      int
+	Body      proto.Message
+	err       error
+	log       logger.Logger
+	hdr       http.Header
+	requestID string
+
+	contentType string
+}
+
+type ProtobufResponsable interface {
+	ToProtobuf() *Protobuf
+}
+
+const (
+	protobufContentType    = "application/x-protobuf"
+	protobufAltContentType = "application/protobuf"
+)
+
+var (
+	InternalServerErrorProtobufBytes []byte
+)
+
+func init() {
+	InternalServerErrorProtobufBytes, _ = proto.Marshal(protobufStatus(defaultJSONError()))
+}
+
+func NewProtobuf(code int, body proto.Message) *Protobuf {
+	return &Protobuf{
+		Code: code,
+		Body: body,
+	}
+}
+
+// NewProtobufError creates a google.rpc.Status response, the message defaults to the status text.
+func NewProtobufError(code int, message string, err error) *Protobuf {
+	if len(message) == 0 {
+		message = http.StatusText(code)
+	}
+
+	return NewProtobuf(code, &status.Status{
+		Code:    HTTPToGRPCCode(code),
+		Message: message,
+	}).WithError(err)
+}
+
+func (r *Protobuf) WithError(err error) *Protobuf {
+	r.err = err
+
+	return r
+}
+
+func (r *Protobuf) WithLogger(log logger.Logger) *Protobuf {
+	r.log = log
+
+	return r
+}
+
+func (r Protobuf) GetCode() int {
+	return r.Code
+}
+
+func (r Protobuf) GetBody() []byte {
+	if r.Body == nil {
+		return []byte{}
+	}
+
+	if b, err := proto.Marshal(r.Body); err == nil {
+		return b
+	}
+
+	requestLog(r.requestID).Warn("Failed to marshal protobuf response")
+
+	return InternalServerErrorProtobufBytes
+}
+
+func (r Protobuf) Handle() (int, []byte) {
+	c := r.GetCode()
+	b := r.GetBody()
+
+	if c == 0 && len(b) == 0 {
+		c = http.StatusInternalServerError
+		b = InternalServerErrorProtobufBytes
+	} else if c == 0 {
+		c = http.StatusOK
+	}
+
+	if r.err != nil && r.log != nil {
+		r.log.WithFields(logFields(c, r.requestID)).WithError(r.err).Log(CodeToLogLevel(c), "Response error encountered")
+	}
+
+	return c, b
+}
+
+func (r Protobuf) GetError() error {
+	return r.err
+}
+
+func (r Protobuf) GetHeaders() http.Header {
+	return r.hdr
+}
+
+func (r Protobuf) GetContentType() string {
+	if len(r.contentType) == 0 {
+		return protobufContentType
+	}
+
+	return r.contentType
+}
+
+// BindRequest answers with the media type the client asked for.
+func (r *Protobuf) BindRequest(req *http.Request) ResponseType {
+	if req == nil || req.Header.Get("Accept") != protobufAltContentType {
+		return r
+	}
+
+	return &Protobuf{contentType: protobufAltContentType}
+}
+
+func (r *Protobuf) Unmarshal(resp interface{}) Response {
+	cResp := r.unmarshal(resp)
+
+	if p, ok := cResp.(*Protobuf); ok && len(p.contentType) == 0 && len(r.contentType) > 0 {
+		c := *p
+		c.contentType = r.contentType
+
+		return &c
+	}
+
+	return cResp
+}
+
+func (r *Protobuf) unmarshal(resp interface{}) Response {
+	if resp == nil {
+		return &Protobuf{Code: http.StatusNoContent}
+	}
+
+	if pr, ok := resp.(ProtobufResponsable); ok {
+		return pr.ToProtobuf()
+	}
+
+	switch cResp := resp.(type) {
+	case Protobuf:
+		return &cResp
+	case *Protobuf:
+		return cResp
+	case proto.Message:
+		c := http.StatusOK
+
+		if coder, ok := resp.(Coder); ok {
+			c = coder.GetCode()
+		}
+
+		return &Protobuf{Code: c, Body: cResp}
+	}
+
+	res := Classify(resp)
+
+	if res == nil {
+		return nil
+	}
+
+	if je, ok := res.ErrorPayload(); ok {
+		return &Protobuf{Code: res.Code, Body: protobufStatus(je), err: res.Err, hdr: res.Header}
+	}
+
+	if res.Payload == nil {
+		return &Protobuf{Code: res.Code, hdr: res.Header}
+	}
+
+	if m, ok := res.Payload.(proto.Message); ok {
+		return &Protobuf{Code: res.Code, Body: m, hdr: res.Header}
+	}
+
+	return nil
+}
+
+func (r *Protobuf) DefaultError() Response {
+	return &Protobuf{
+		Code:        http.StatusInternalServerError,
+		Body:        protobufStatus(defaultJSONError()),
+		contentType: r.contentType,
+	}
+}
+
+func (r *Protobuf) GetAcceptedType() string {
+	return protobufContentType
+}
+
+func (r *Protobuf) GetAcceptedAliases() []string {
+	return []string{protobufAltContentType}
+}
+
+func (r *Protobuf) String() string {
+	return r.GetAcceptedType()
+}
+
+func protobufStatus(je JSONError) *status.Status {
+	msg, ok := je.Description.(string)
+
+	if !ok && je.Description != nil {
+		msg = fmt.Sprint(je.Description)
+	}
+
+	return &status.Status{
+		Code:    HTTPToGRPCCode(je.Code),
+		Message: msg,
+	}
+}
+
+// HTTPToGRPCCode maps a http status code to the closest gRPC status code,
+// the reverse of the mapping used by gRPC-gateway.
+func HTTPToGRPCCode(code int) int32 {
+	switch code {
+	case http.StatusOK:
+		return 0 // OK
+	case 499:
+		return 1 // Canceled
+	case http.StatusBadRequest:
+		return 3 // InvalidArgument
+	case http.StatusGatewayTimeout:
+		return 4 // DeadlineExceeded
+	case http.StatusNotFound:
+		return 5 // NotFound
+	case http.StatusConflict:
+		return 6 // AlreadyExists
+	case http.StatusForbidden:
+		return 7 // PermissionDenied
+	case http.StatusTooManyRequests:
+		return 8 // ResourceExhausted
+	case http.StatusPreconditionFailed:
+		return 9 // FailedPrecondition
+	case http.StatusNotImplemented:
+		return 12 // Unimplemented
+	case http.StatusInternalServerError:
+		return 13 // Internal
+	case http.StatusServiceUnavailable:
+		return 14 // Unavailable
+	case http.StatusUnauthorized:
+		return 16 // Unauthenticated
+	}
+
+	switch {
+	case code >= 200 && code < 300:
+		return 0 // OK
+	case code >= 400 && code < 500:
+		return 3 // InvalidArgument
+	}
+
+	return 2 // Unknown
+}
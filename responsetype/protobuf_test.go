@@ -0,0 +1,150 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"testing"
+)
+
+type ProtobufResponsableMock struct {
+	code int
+	body proto.Message
+}
+
+func (pr ProtobufResponsableMock) ToProtobuf() *Protobuf {
+	return &Protobuf{Code: pr.code, Body: pr.body}
+}
+
+type ProtobufCoderMock struct {
+	*wrapperspb.StringValue
+}
+
+func (pcm ProtobufCoderMock) GetCode() int {
+	return http.StatusAccepted
+}
+
+func mustMarshalProto(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(m)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestProtobuf_Unmarshal(t *testing.T) {
+	root := &Protobuf{}
+
+	msg := wrapperspb.String("testmessage")
+
+	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(complex(1, 2)), -1, nil)
+	expectResponse(t, root.Unmarshal(map[string]string{}), -1, nil)
+	expectResponse(t, root.Unmarshal(uint16(http.StatusResetContent)), http.StatusResetContent, []byte(""))
+
+	expectResponse(t, root.Unmarshal(msg), http.StatusOK, mustMarshalProto(t, msg))
+	expectResponse(t, root.Unmarshal(ProtobufCoderMock{msg}), http.StatusAccepted, mustMarshalProto(t, msg))
+	expectResponse(t, root.Unmarshal(NewProtobuf(http.StatusCreated, msg)), http.StatusCreated, mustMarshalProto(t, msg))
+	expectResponse(t, root.Unmarshal(ProtobufResponsableMock{code: http.StatusAccepted, body: msg}), http.StatusAccepted, mustMarshalProto(t, msg))
+
+	expectResponse(t, root.Unmarshal(errors.New("testerror")), http.StatusInternalServerError, InternalServerErrorProtobufBytes)
+	expectResponse(t, root.Unmarshal(CoderError(http.StatusNotFound)), http.StatusNotFound, mustMarshalProto(t, &status.Status{Code: 5, Message: "Internal Server Error"}))
+	expectResponse(t, root.Unmarshal(JSONError{Code: http.StatusTooManyRequests, Description: 12}), http.StatusTooManyRequests, mustMarshalProto(t, &status.Status{Code: 8, Message: "12"}))
+	expectResponse(t, root.Unmarshal(NewProtobufError(http.StatusUnauthorized, "", nil)), http.StatusUnauthorized, mustMarshalProto(t, &status.Status{Code: 16, Message: "Unauthorized"}))
+
+	expectResponse(t, root.DefaultError(), http.StatusInternalServerError, InternalServerErrorProtobufBytes)
+}
+
+func TestProtobuf_BindRequest(t *testing.T) {
+	root := &Protobuf{}
+
+	req := &http.Request{Header: http.Header{"Accept": []string{"application/x-protobuf"}}}
+
+	if root.BindRequest(req) != root {
+		t.Error("Expected default content type to return the type as is")
+	}
+
+	req.Header.Set("Accept", "application/protobuf")
+
+	bound := root.BindRequest(req)
+
+	ect := "application/protobuf"
+
+	if ct := bound.Unmarshal(wrapperspb.String("a")).GetContentType(); ct != ect {
+		t.Errorf("Invalid bound content type, expected %s, got %s", ect, ct)
+	}
+
+	if ct := bound.DefaultError().GetContentType(); ct != ect {
+		t.Errorf("Invalid bound error content type, expected %s, got %s", ect, ct)
+	}
+
+	shared := NewProtobuf(http.StatusOK, wrapperspb.String("a"))
+	_ = bound.Unmarshal(shared)
+
+	if len(shared.contentType) > 0 {
+		t.Error("Expected returned responses not to be modified")
+	}
+}
+
+func TestProtobuf_Handle(t *testing.T) {
+	c, b := (&Protobuf{}).Handle()
+
+	if c != http.StatusInternalServerError {
+		t.Errorf("Invalid code returned, expected %d, got %d", http.StatusInternalServerError, c)
+	}
+	if bytes.Compare(b, InternalServerErrorProtobufBytes) != 0 {
+		t.Errorf("Invalid body returned, expected %v, got %v", InternalServerErrorProtobufBytes, b)
+	}
+
+	c, _ = (&Protobuf{Body: wrapperspb.String("a")}).Handle()
+
+	if c != http.StatusOK {
+		t.Errorf("Invalid code returned, expected %d, got %d", http.StatusOK, c)
+	}
+}
+
+func TestHTTPToGRPCCode(t *testing.T) {
+	expected := map[int]int32{
+		http.StatusOK:                  0,
+		http.StatusNoContent:           0,
+		http.StatusBadRequest:          3,
+		http.StatusUnauthorized:        16,
+		http.StatusForbidden:           7,
+		http.StatusNotFound:            5,
+		http.StatusTeapot:              3,
+		http.StatusInternalServerError: 13,
+		http.StatusBadGateway:          2,
+		0:                              2,
+	}
+
+	for code, grpc := range expected {
+		if got := HTTPToGRPCCode(code); got != grpc {
+			t.Errorf("Invalid gRPC code for %d, expected %d, got %d", code, grpc, got)
+		}
+	}
+}
+
+func TestProtobuf_GetAcceptedType(t *testing.T) {
+	root := &Protobuf{}
+
+	expected := "application/x-protobuf"
+
+	if got := root.GetAcceptedType(); got != expected {
+		t.Errorf("Invalid value for accepted types, expected %s, got %s", expected, got)
+	}
+
+	if got := root.String(); got != expected {
+		t.Errorf("Invalid value for string, expected %s, got %s", expected, got)
+	}
+
+	if got := root.GetAcceptedAliases(); len(got) != 1 || got[0] != "application/protobuf" {
+		t.Errorf("Invalid accepted aliases, expected %v, got %v", []string{"application/protobuf"}, got)
+	}
+}
@@ -0,0 +1,146 @@
+package responsetype
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// logFields returns the fields of a response error log entry
+func logFields(c int, requestID string) logrus.Fields {
+	f := logrus.Fields{
+		"code":   c,
+		"status": CodeToStatus(c),
+	}
+
+	if len(requestID) > 0 {
+		f["request_id"] = requestID
+	}
+
+	return f
+}
+
+// requestLog returns the package logger, including the request ID when known
+func requestLog(requestID string) *logrus.Entry {
+	f := logrus.Fields{}
+
+	if len(requestID) > 0 {
+		f["request_id"] = requestID
+	}
+
+	return log.WithFields(f)
+}
+
+// withRequestID returns a copy of a JSONError payload including the request ID,
+// other payloads are returned as is
+func withRequestID(payload interface{}, id string) interface{} {
+	switch je := payload.(type) {
+	case JSONError:
+		je.RequestID = id
+
+		return je
+	case *JSONError:
+		if je == nil {
+			return payload
+		}
+
+		c := *je
+		c.RequestID = id
+
+		return &c
+	}
+
+	return payload
+}
+
+func (r *JSON) BindRequestID(id string) Response {
+	c := *r
+	c.requestID = id
+	c.Body = withRequestID(r.Body, id)
+
+	return &c
+}
+
+// BindRequestID includes the request ID in the terminal error line of a stream,
+// or in the error of a single document
+func (r *NDJSON) BindRequestID(id string) Response {
+	c := *r
+	c.requestID = id
+
+	if b, ok := r.doc.(RequestIDBinder); ok {
+		c.doc = b.BindRequestID(id)
+
+		_, body := c.doc.Handle()
+		c.Body = json.RawMessage(body)
+	}
+
+	return &c
+}
+
+func (r *CSV) BindRequestID(id string) Response {
+	c := *r
+	c.requestID = id
+
+	return &c
+}
+
+// BindRequestID keeps writing the rows as they are produced
+func (r *csvStream) BindRequestID(id string) Response {
+	return &csvStream{CSV: r.CSV.BindRequestID(id).(*CSV)}
+}
+
+func (r *HTML) BindRequestID(id string) Response {
+	c := *r
+	c.requestID = id
+
+	return &c
+}
+
+// BindRequestID adds a google.rpc.RequestInfo detail to google.rpc.Status bodies
+func (r *Protobuf) BindRequestID(id string) Response {
+	c := *r
+	c.requestID = id
+
+	st, ok := r.Body.(*status.Status)
+
+	if !ok || st == nil {
+		return &c
+	}
+
+	detail, err := anypb.New(&errdetails.RequestInfo{RequestId: id})
+
+	if err != nil {
+		return &c
+	}
+
+	st = proto.Clone(st).(*status.Status)
+	st.Details = append(st.Details, detail)
+
+	c.Body = st
+
+	return &c
+}
+
+func (r *encoded) BindRequestID(id string) Response {
+	res := *r.res
+	res.Payload = withRequestID(res.Payload, id)
+
+	c := *r
+	c.res = &res
+	c.requestID = id
+
+	return &c
+}
+
+func (r *jsonpResponse) BindRequestID(id string) Response {
+	c := *r
+
+	if b, ok := r.Response.(RequestIDBinder); ok {
+		c.Response = b.BindRequestID(id)
+	}
+
+	return &c
+}
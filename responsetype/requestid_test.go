@@ -0,0 +1,139 @@
+package responsetype
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestJSON_BindRequestID(t *testing.T) {
+	je := &JSONError{Code: http.StatusBadRequest, Description: "invalid"}
+
+	r := NewJSON(http.StatusBadRequest, je)
+
+	expectResponse(t, r.BindRequestID("abc"), http.StatusBadRequest, []byte(`{"code":400,"description":"invalid","request_id":"abc"}`))
+	expectResponse(t, r, http.StatusBadRequest, []byte(`{"code":400,"description":"invalid"}`))
+
+	if len(je.RequestID) > 0 {
+		t.Errorf("Expected the original error to be unchanged, got %s", je.RequestID)
+	}
+
+	r = NewJSON(http.StatusOK, map[string]int{"a": 1})
+
+	expectResponse(t, r.BindRequestID("abc"), http.StatusOK, []byte(`{"a":1}`))
+}
+
+func TestNDJSON_BindRequestID(t *testing.T) {
+	single := TypeNDJSON.Unmarshal(errors.New("testerror")).(RequestIDBinder).BindRequestID("abc")
+
+	buf := &bytes.Buffer{}
+
+	if err := single.(Streamer).Stream(buf); err != nil {
+		t.Errorf("Unexpected stream error %v", err)
+	}
+
+	expected := `{"code":500,"description":"Internal Server Error","request_id":"abc"}` + "\n"
+
+	if b := buf.String(); b != expected {
+		t.Errorf("Invalid single document, expected %s, got %s", expected, b)
+	}
+
+	stream := NewNDJSON(http.StatusOK, []interface{}{1, errors.New("testerror")}).BindRequestID("abc")
+
+	buf.Reset()
+
+	_ = stream.(Streamer).Stream(buf)
+
+	expected = "1\n" + `{"error":{"code":500,"description":"Internal Server Error","request_id":"abc"}}` + "\n"
+
+	if b := buf.String(); b != expected {
+		t.Errorf("Invalid stream, expected %s, got %s", expected, b)
+	}
+}
+
+func TestCSV_BindRequestID(t *testing.T) {
+	stream := (&CSV{}).WithOptions(CSVOptions{Stream: true}).Unmarshal([][]string{{"a"}, {"b"}})
+
+	bound := stream.(RequestIDBinder).BindRequestID("abc")
+
+	if _, ok := bound.(Streamer); !ok {
+		t.Fatalf("Expected the bound response to stream, got %T", bound)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := bound.(Streamer).Stream(buf); err != nil || buf.String() != "a\nb\n" {
+		t.Errorf("Invalid stream, expected %q, got %q (%v)", "a\nb\n", buf.String(), err)
+	}
+}
+
+func TestProtobuf_BindRequestID(t *testing.T) {
+	st := &status.Status{Code: 13, Message: "Internal Server Error"}
+
+	r := NewProtobuf(http.StatusInternalServerError, st).BindRequestID("abc")
+
+	got := &status.Status{}
+
+	if err := proto.Unmarshal(r.(*Protobuf).GetBody(), got); err != nil {
+		t.Fatalf("Invalid protobuf body, got error %v", err)
+	}
+
+	if len(got.Details) != 1 {
+		t.Fatalf("Expected 1 detail, got %d", len(got.Details))
+	}
+
+	ri := &errdetails.RequestInfo{}
+
+	if err := got.Details[0].UnmarshalTo(ri); err != nil || ri.RequestId != "abc" {
+		t.Errorf("Invalid request info detail, expected abc, got %s (%v)", ri.RequestId, err)
+	}
+
+	if len(st.Details) > 0 {
+		t.Errorf("Expected the original status to be unchanged")
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	l := logrus.New()
+	l.Out = buf
+
+	orig, called := log, logMockCalled
+	defer func() { log, logMockCalled = orig, called }()
+
+	log = LogMock{Entry: l.WithField("test", true)}
+
+	responses := map[string]Response{
+		"json":    NewJSON(http.StatusOK, make(chan int)),
+		"csv":     NewCSV(http.StatusOK, []interface{}{1, errors.New("testerror")}),
+		"html":    NewHTML(http.StatusOK, "page", nil),
+		"encoded": TypeMsgPack.Unmarshal(map[string]interface{}{"a": make(chan int)}),
+	}
+
+	for name, r := range responses {
+		buf.Reset()
+
+		_, _ = r.(RequestIDBinder).BindRequestID("abc").Handle()
+
+		if !strings.Contains(buf.String(), "request_id=abc") {
+			t.Errorf("Expected the request id in the %s log entry, got %s", name, buf.String())
+		}
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	if f := logFields(http.StatusNotFound, ""); len(f) != 2 {
+		t.Errorf("Expected 2 fields without request id, got %v", f)
+	}
+
+	if f := logFields(http.StatusNotFound, "abc"); f["request_id"] != "abc" {
+		t.Errorf("Expected request id field, got %v", f)
+	}
+}
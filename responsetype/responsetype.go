@@ -1,7 +1,9 @@
 package responsetype
 
 import (
+	"context"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 )
 
@@ -16,6 +18,39 @@ type Coder interface {
 	GetCode() int
 }
 
+// Headerer is implemented by responses which set additional response headers.
+type Headerer interface {
+	GetHeaders() http.Header
+}
+
+// Errorer is implemented by responses carrying the error they were created for, e.g. through WithError.
+type Errorer interface {
+	GetError() error
+}
+
+// RequestIDBinder is implemented by responses which include the request ID
+// in their log entries and error bodies. The response is copied, it might be shared between requests.
+type RequestIDBinder interface {
+	BindRequestID(id string) Response
+}
+
+// AliasAccepter is implemented by response types accepting more than one media type.
+type AliasAccepter interface {
+	GetAcceptedAliases() []string
+}
+
+// Streamer is implemented by responses which write their body while it is produced.
+// Handle remains available and buffers the complete stream.
+type Streamer interface {
+	Stream(w io.Writer) error
+}
+
+// ContextStreamer is implemented by streamers which stop when the context is done,
+// e.g. while waiting for the next element of a channel. The request context is passed.
+type ContextStreamer interface {
+	StreamContext(ctx context.Context, w io.Writer) error
+}
+
 type TypeHandler interface {
 	GetAcceptedType() string
 	Unmarshal(resp interface{}) Response
@@ -25,8 +60,20 @@ type TypeHandler interface {
 
 type ResponseType TypeHandler
 
+// RequestBinder is implemented by response types whose output depends on the request,
+// e.g. on query parameters. The returned type is used for that request only.
+type RequestBinder interface {
+	BindRequest(r *http.Request) ResponseType
+}
+
 var (
-	TypeJSON ResponseType = &JSON{}
+	TypeJSON     ResponseType = &JSON{}
+	TypeJSONP    ResponseType = &JSONP{}
+	TypeNDJSON   ResponseType = &NDJSON{}
+	TypeCSV      ResponseType = &CSV{}
+	TypeProtobuf ResponseType = &Protobuf{}
+	TypeMsgPack  ResponseType = NewEncoderType(MsgPack{})
+	TypeCBOR     ResponseType = NewEncoderType(CBOR{})
 	//TypePlainText =
 )
 
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
+	"net/url"
 	"peterdekok.nl/gotools/logger"
 	"testing"
 )
@@ -70,6 +72,21 @@ func (jr JSONResponsableMock) ToJSON() *JSON {
 	return &JSON{Code: jr.code, Body: jr.body}
 }
 
+type JSONEncoderMock struct {
+	w      io.Writer
+	indent string
+	escape bool
+}
+
+func (jem *JSONEncoderMock) Encode(v interface{}) error {
+	_, err := fmt.Fprintf(jem.w, "mock:%v:%s:%t\n", v, jem.indent, jem.escape)
+
+	return err
+}
+
+func (jem *JSONEncoderMock) SetIndent(_, indent string) { jem.indent = indent }
+func (jem *JSONEncoderMock) SetEscapeHTML(on bool)      { jem.escape = on }
+
 type LogMock struct {
 	*logrus.Entry
 }
@@ -164,6 +181,33 @@ func TestJSON_WithError(t *testing.T) {
 	}
 }
 
+func TestErrorer(t *testing.T) {
+	err := errors.New("testerror")
+
+	responses := map[string]Response{
+		"json":     NewJSON(http.StatusInternalServerError, nil).WithError(err),
+		"ndjson":   NewNDJSON(http.StatusInternalServerError, nil).WithError(err),
+		"csv":      NewCSV(http.StatusInternalServerError, nil).WithError(err),
+		"protobuf": NewProtobuf(http.StatusInternalServerError, nil).WithError(err),
+		"jsonp":    (&JSONP{}).BindRequest(&http.Request{URL: &url.URL{RawQuery: "callback=cb"}}).Unmarshal(err),
+		"msgpack":  TypeMsgPack.Unmarshal(err),
+	}
+
+	for name, r := range responses {
+		e, ok := r.(Errorer)
+
+		if !ok {
+			t.Errorf("Expected %s response to implement Errorer", name)
+
+			continue
+		}
+
+		if got := e.GetError(); got != err {
+			t.Errorf("Invalid %s response error, expected %v, got %v", name, err, got)
+		}
+	}
+}
+
 func TestJSON_WithLogger(t *testing.T) {
 	rt := NewJSON(http.StatusFailedDependency, "testbody")
 
@@ -183,6 +227,146 @@ func TestJSON_WithLogger(t *testing.T) {
 	}
 }
 
+func TestJSON_WithHeader(t *testing.T) {
+	rt := NewJSON(http.StatusOK, "testbody")
+
+	if rt.GetHeaders() != nil {
+		t.Error("expected no headers by default")
+	}
+
+	rtH := rt.WithHeader("X-Test", "a").WithHeader("X-Test", "b")
+
+	if rt != rtH {
+		t.Error("expected response to be fluent")
+	}
+
+	eh := []string{"a", "b"}
+	h := rt.GetHeaders()["X-Test"]
+
+	if len(h) != len(eh) || h[0] != eh[0] || h[1] != eh[1] {
+		t.Errorf("invalid json headers, expected %v, got %v", eh, h)
+	}
+}
+
+func TestJSON_WithOptions(t *testing.T) {
+	rt := NewJSON(http.StatusOK, "testbody")
+
+	rtO := rt.WithOptions(JSONOptions{Indent: "\t"})
+
+	if rt != rtO {
+		t.Error("expected response to be fluent")
+	}
+
+	ei := "\t"
+	i := rt.opts.Indent
+
+	if i != ei {
+		t.Errorf("invalid json options indent, expected %q, got %q", ei, i)
+	}
+}
+
+func TestJSON_GetBody_Options(t *testing.T) {
+	body := map[string]string{"url": "https://example.com/?a=1&b=<2>"}
+
+	expected := []byte("{\"url\":\"https://example.com/?a=1\\u0026b=\\u003c2\\u003e\"}")
+	got := (&JSON{Body: body}).WithOptions(JSONOptions{}).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", expected, got)
+	}
+
+	expected = []byte("{\"url\":\"https://example.com/?a=1&b=<2>\"}")
+	got = (&JSON{Body: body}).WithOptions(JSONOptions{DisableHTMLEscape: true}).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", expected, got)
+	}
+
+	expected = []byte("{\n  \"url\": \"https://example.com/?a=1&b=<2>\"\n}")
+	got = (&JSON{Body: body}).WithOptions(JSONOptions{Indent: "  ", DisableHTMLEscape: true}).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", expected, got)
+	}
+
+	// Marshaling for embedding never indents
+	expected = []byte("{\"url\":\"https://example.com/?a=1&b=<2>\"}")
+	got, _ = (&JSON{Body: body}).WithOptions(JSONOptions{Indent: "  ", DisableHTMLEscape: true}).MarshalJSON()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid marshaled json, expected %s, got %s", expected, got)
+	}
+
+	opts := JSONOptions{
+		Indent:     "--",
+		NewEncoder: func(w io.Writer) JSONEncoder { return &JSONEncoderMock{w: w} },
+	}
+
+	expected = []byte("mock:[testbody]:--:true")
+	got = (&JSON{Body: []string{"testbody"}}).WithOptions(opts).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid body returned, expected %s, got %s", expected, got)
+	}
+
+	root := (&JSON{}).WithOptions(opts)
+	got = root.Unmarshal(map[string]string{}).GetBody()
+	expected = []byte("mock:map[]:--:true")
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Expected unmarshaled response to inherit options, expected %s, got %s", expected, got)
+	}
+
+	shared := &JSON{Code: http.StatusOK, Body: []string{}}
+	_ = root.Unmarshal(shared)
+
+	if shared.opts != nil {
+		t.Error("Expected returned responses not to be modified")
+	}
+}
+
+func TestJSON_BindRequest(t *testing.T) {
+	req := &http.Request{URL: &url.URL{RawQuery: "pretty"}}
+
+	root := &JSON{}
+
+	if root.BindRequest(req) != root {
+		t.Error("Expected response type without options to be returned as is")
+	}
+
+	root.WithOptions(JSONOptions{})
+
+	if root.BindRequest(req) != root {
+		t.Error("Expected response type without pretty query to be returned as is")
+	}
+
+	root.WithOptions(JSONOptions{PrettyQuery: true})
+
+	for q, pretty := range map[string]bool{"": false, "pretty": true, "pretty=1": true, "pretty=false": false, "pretty=0": false, "a=b&pretty=": true} {
+		bound := root.BindRequest(&http.Request{URL: &url.URL{RawQuery: q}})
+
+		if got := bound != root; got != pretty {
+			t.Errorf("Invalid pretty binding for query %q, expected %t, got %t", q, pretty, got)
+		}
+	}
+
+	expected := []byte("[\n  \"a\"\n]")
+	got := root.BindRequest(req).Unmarshal([]string{"a"}).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid pretty body, expected %s, got %s", expected, got)
+	}
+
+	root.opts.Indent = "\t"
+
+	expected = []byte("[\n\t\"a\"\n]")
+	got = root.BindRequest(req).Unmarshal([]string{"a"}).GetBody()
+
+	if bytes.Compare(got, expected) != 0 {
+		t.Errorf("Invalid pretty body, expected %s, got %s", expected, got)
+	}
+}
+
 func TestJSON_DefaultError(t *testing.T) {
 	errResp := (&JSON{}).DefaultError().GetBody()
 
@@ -215,7 +399,7 @@ func TestJSON_Unmarshal(t *testing.T) {
 	root := &JSON{}
 
 	expectResponse(t, root.Unmarshal(nil), http.StatusNoContent, []byte(""))
-	expectResponse(t, root.Unmarshal(true), -1, nil)
+	expectResponse(t, root.Unmarshal(complex(1, 2)), -1, nil)
 
 	jr := &JSONResponsableMock{code: http.StatusUnauthorized, body: "testjsonresponsable"}
 	expectResponse(t, root.Unmarshal(jr), http.StatusUnauthorized, []byte("testjsonresponsable"))
@@ -252,6 +436,20 @@ func TestJSON_Unmarshal(t *testing.T) {
 	expectResponse(t, root.Unmarshal([]string{"jsonmarshalslice", "second"}), http.StatusOK, []byte("[\"jsonmarshalslice\",\"second\"]"))
 	expectResponse(t, root.Unmarshal([2]string{"jsonmarshalarray", "second"}), http.StatusOK, []byte("[\"jsonmarshalarray\",\"second\"]"))
 
+	expectResponse(t, root.Unmarshal(true), http.StatusOK, []byte("true"))
+	expectResponse(t, root.Unmarshal(1.5), http.StatusOK, []byte("1.5"))
+	expectResponse(t, root.Unmarshal(int64(http.StatusConflict)), http.StatusConflict, []byte(""))
+	expectResponse(t, root.Unmarshal(uint(http.StatusConflict)), http.StatusConflict, []byte(""))
+	expectResponse(t, root.Unmarshal(namedInt(3)), http.StatusOK, []byte("3"))
+	expectResponse(t, root.Unmarshal(namedString("named")), http.StatusOK, []byte("\"named\""))
+	expectResponse(t, root.Unmarshal(struct {
+		Name string `json:"name"`
+		Skip string `json:"-"`
+	}{Name: "jsonmarshalstruct"}), http.StatusOK, []byte("{\"name\":\"jsonmarshalstruct\"}"))
+	expectResponse(t, root.Unmarshal(&csvRow{Name: "ptr"}), http.StatusOK, []byte("{\"Name\":\"ptr\",\"Age\":0,\"Skipped\":\"\",\"Score\":null,\"Created\":\"0001-01-01T00:00:00Z\"}"))
+	expectResponse(t, root.Unmarshal((*csvRow)(nil)), http.StatusNoContent, []byte(""))
+	expectResponse(t, root.Unmarshal(coderStruct{code: http.StatusCreated}), http.StatusCreated, []byte("{}"))
+
 	// TODO map
 	// TODO slice
 	// TODO array
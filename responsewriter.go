@@ -1,11 +1,13 @@
 package responsewriter
 
 import (
+	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"peterdekok.nl/gotools/logger"
 	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"time"
 )
 
 type Handler func(r *Request) interface{}
@@ -20,7 +22,26 @@ func init() {
 	log = logger.New("responsewriter")
 }
 
+// ResponseHandler serves the return value of the handler with the negotiated response type,
+// using the default Writer
 func ResponseHandler(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) httprouter.Handle {
+	return defaultWriter.Handler(handler, preferredType, allowedTypes...)
+}
+
+// responder serves the return value of a handler with the negotiated response type,
+// independent of the router calling it
+type responder struct {
+	handler       Handler
+	preferredType ResponseType
+	types         map[string]ResponseType
+	// negotiated is set when the Accept header selects between response types
+	negotiated bool
+
+	// writer is set by the Writer creating the responder
+	writer *Writer
+}
+
+func newResponder(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) *responder {
 	if preferredType == nil {
 		panic("Invalid response type given for response handler")
 	}
@@ -34,61 +55,308 @@ func ResponseHandler(handler Handler, preferredType ResponseType, allowedTypes .
 	types := make(map[string]ResponseType)
 
 	for _, allowedType := range allowedTypes {
-		types[allowedType.GetAcceptedType()] = allowedType
+		registerType(types, allowedType)
 	}
 
-	types[preferredAcceptedType] = preferredType
+	registerType(types, preferredType)
 
-	return func(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
-		at := req.Header.Get("Accept")
+	negotiated := false
 
-		t := preferredType
+	for _, t := range types {
+		negotiated = negotiated || t.GetAcceptedType() != preferredAcceptedType
+	}
 
-		if st, ok := types[at]; ok && len(at) > 0 && st != nil {
-			t = st
-		}
+	return &responder{
+		handler:       handler,
+		preferredType: preferredType,
+		types:         types,
+		negotiated:    negotiated,
+	}
+}
 
-		resp := handler(NewRequest(req, p))
+func (rs *responder) serve(w http.ResponseWriter, r *Request) {
+	wr := rs.writer
 
-		cResp := t.Unmarshal(resp)
+	r.Route = wr.route
 
-		if cResp == nil {
-			var ok bool
+	if wr.requestID != nil {
+		wr.bindRequestID(w, r)
+	}
 
-			cResp, ok = resp.(responsetype.Response)
+	if wr.security != nil {
+		w = &securityWriter{ResponseWriter: w, headers: wr.security, tls: r.TLS != nil}
+	}
 
-			if !ok {
-				log.WithField("responsetype", t).Error("Unrecognized response, serving default error")
+	if wr.metrics == nil && wr.tracer == nil {
+		rs.respond(w, r)
 
-				cResp = t.DefaultError()
-			}
+		return
+	}
+
+	start := time.Now()
+	cw := &countingWriter{ResponseWriter: w}
+
+	span := wr.startSpan(r)
+
+	o := rs.respond(cw, r)
+
+	o.code = cw.code
+	o.size = cw.size
+
+	if o.code == 0 {
+		o.code = http.StatusOK
+	}
+
+	if span != nil {
+		endSpan(span, o)
+	}
+
+	if wr.metrics != nil {
+		wr.metrics.Observe(Observation{
+			Route:    wr.route,
+			Method:   r.Method,
+			Code:     o.code,
+			Type:     o.t.GetAcceptedType(),
+			Duration: time.Since(start),
+			Size:     o.size,
+			Fallback: o.fallback,
+		})
+	}
+}
+
+// outcome describes the written response
+type outcome struct {
+	t        ResponseType
+	fallback bool
+	err      error
+	code     int
+	size     int
+}
+
+// respond writes the response, returning the negotiated type, the error of the response and whether
+// the default error was served because the return value was not recognized
+func (rs *responder) respond(w http.ResponseWriter, r *Request) *outcome {
+	o := &outcome{}
+
+	at := r.Header.Get("Accept")
+
+	t := rs.preferredType
+
+	if st, ok := rs.types[at]; ok && len(at) > 0 && st != nil {
+		t = st
+	}
+
+	if rb, ok := t.(responsetype.RequestBinder); ok {
+		t = rb.BindRequest(r.Request)
+	}
+
+	r.responseType = t
+
+	if rs.negotiated {
+		addVary(r.ResponseHeader(), "Accept")
+	}
+
+	resp := rs.call(r)
+
+	switch stored := resp.(type) {
+	case *cacheHit:
+		o.t = t
+		stored.write(w, r)
+
+		return o
+	case *idempotentReplay:
+		o.t = t
+		stored.write(w, r)
+
+		return o
+	}
+
+	if r.cache != nil {
+		w = r.cache.record(w)
+		defer r.cache.complete(r)
+	}
+
+	if r.idempotency != nil {
+		w = r.idempotency.record(w)
+		defer r.idempotency.complete(r)
+	}
+
+	cResp := t.Unmarshal(resp)
+
+	if cResp == nil {
+		var ok bool
+
+		cResp, ok = resp.(responsetype.Response)
+
+		if !ok {
+			r.logger().WithField("responsetype", t).
+				WithField("type", fmt.Sprintf("%T", resp)).
+				Error("Unrecognized response, serving default error")
+
+			cResp = t.DefaultError()
+			o.fallback = true
 		}
+	}
+
+	o.t = t
+
+	if b, ok := cResp.(responsetype.RequestIDBinder); ok && len(r.RequestID) > 0 {
+		cResp = b.BindRequestID(r.RequestID)
+	}
 
-		c, b := cResp.Handle()
+	if e, ok := cResp.(responsetype.Errorer); ok {
+		o.err = e.GetError()
+	}
 
-		if b != nil {
-			ct := cResp.GetContentType()
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
 
-			if len(ct) == 0 {
-				ct = "text/plain"
+	if h, ok := cResp.(responsetype.Headerer); ok {
+		for k, v := range h.GetHeaders() {
+			if k == "Vary" {
+				addVary(w.Header(), v...)
+			} else {
+				w.Header()[k] = v
 			}
+		}
+	}
+
+	if s, ok := cResp.(responsetype.Streamer); ok {
+		if c := cResp.GetCode(); c == 0 || validCode(c) {
+			stream(w, r, cResp, s)
+
+			return o
+		}
+
+		cResp = invalidCode(r, t, cResp.GetCode())
+		o.fallback = true
+	}
+
+	c, b := cResp.Handle()
+
+	if !validCode(c) {
+		cResp = invalidCode(r, t, c)
+		o.fallback = true
+
+		c, b = cResp.Handle()
+	}
+
+	if b != nil {
+		ct := cResp.GetContentType()
 
-			w.Header().Set("Content-Type", ct)
-		} else if c == http.StatusOK {
-			c = http.StatusNoContent
+		if len(ct) == 0 {
+			ct = "text/plain"
 		}
 
-		w.WriteHeader(c)
+		w.Header().Set("Content-Type", ct)
+	} else if c == http.StatusOK {
+		c = http.StatusNoContent
+	}
+
+	w.WriteHeader(c)
+
+	if b == nil {
+		return o
+	}
+
+	if _, err := w.Write(b); err != nil {
+		r.logger().WithFields(logrus.Fields{
+			"code":   c,
+			"status": responsetype.CodeToStatus(c),
+		}).WithError(err).Error("Failed to write response body")
+	}
+
+	return o
+}
+
+// call calls the handler, unless the request is a preflight request answered by CORS
+func (rs *responder) call(r *Request) interface{} {
+	if wr := rs.writer; wr != nil && wr.cors != nil && wr.cors.apply(r) {
+		return Status(http.StatusNoContent)
+	}
+
+	return rs.handler(r)
+}
+
+// validCode reports whether the status code can be written
+func validCode(c int) bool {
+	return c >= 100 && c <= 599
+}
+
+// invalidCode logs the status code and returns the default error of the response type
+func invalidCode(r *Request, t ResponseType, c int) responsetype.Response {
+	r.logger().WithField("responsetype", t).
+		WithField("code", c).
+		Error("Invalid status code, serving default error")
+
+	return t.DefaultError()
+}
 
-		if b == nil {
-			return
+// registerType maps the accepted type and its aliases to the response type
+func registerType(types map[string]ResponseType, t ResponseType) {
+	types[t.GetAcceptedType()] = t
+
+	if aa, ok := t.(responsetype.AliasAccepter); ok {
+		for _, alias := range aa.GetAcceptedAliases() {
+			types[alias] = t
 		}
+	}
+}
 
-		if _, err := w.Write(b); err != nil {
-			log.WithFields(logrus.Fields{
-				"code":   c,
-				"status": responsetype.CodeToStatus(c),
-			}).WithError(err).Error("Failed to write response body")
+// stream writes the response body while it is produced, the status code can not change
+// once the first line is written. Errors are logged, the response is likely incomplete.
+// The stream stops when the request is done, if supported by the response.
+func stream(w http.ResponseWriter, r *Request, cResp responsetype.Response, s responsetype.Streamer) {
+	c := cResp.GetCode()
+
+	if c == 0 {
+		c = http.StatusOK
+	}
+
+	if c != http.StatusNoContent {
+		ct := cResp.GetContentType()
+
+		if len(ct) == 0 {
+			ct = "text/plain"
 		}
+
+		w.Header().Set("Content-Type", ct)
 	}
+
+	w.WriteHeader(c)
+
+	if c == http.StatusNoContent {
+		return
+	}
+
+	var err error
+
+	if cs, ok := s.(responsetype.ContextStreamer); ok {
+		err = cs.StreamContext(r.Context(), flushWriter{w})
+	} else {
+		err = s.Stream(flushWriter{w})
+	}
+
+	if err != nil {
+		r.logger().WithFields(logrus.Fields{
+			"code":   c,
+			"status": responsetype.CodeToStatus(c),
+		}).WithError(err).Error("Failed to stream response body")
+	}
+}
+
+// flushWriter flushes every write, so each streamed chunk reaches the client immediately
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+
+	if f, ok := fw.w.(http.Flusher); ok && err == nil {
+		f.Flush()
+	}
+
+	return n, err
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/http/httptest"
 	"peterdekok.nl/gotools/logger"
 	"peterdekok.nl/gotools/responsewriter/responsetype"
 	"testing"
@@ -45,6 +46,16 @@ func (mrt mockResponseType) String() string                                { ret
 func (mrt mockResponseType) Unmarshal(_ interface{}) responsetype.Response { return mrt.resp }
 func (mrt mockResponseType) DefaultError() responsetype.Response           { return mrt.defResp }
 
+type mockBindingResponseType struct {
+	mockResponseType
+
+	bound *mockResponseType
+}
+
+func (mbrt mockBindingResponseType) BindRequest(_ *http.Request) responsetype.ResponseType {
+	return mbrt.bound
+}
+
 type hoBag struct {
 	h        http.Header
 	b        [][]byte
@@ -66,12 +77,14 @@ type mockResponse struct {
 	code int
 	body []byte
 	ctt  string
+	hdr  http.Header
 }
 
-func (mr *mockResponse) Handle() (int, []byte)  { return mr.code, mr.body }
-func (mr *mockResponse) GetBody() []byte        { panic("NOIMPL") }
-func (mr *mockResponse) GetContentType() string { return mr.ctt }
-func (mr *mockResponse) GetCode() int           { panic("NOIMPL") }
+func (mr *mockResponse) Handle() (int, []byte)   { return mr.code, mr.body }
+func (mr *mockResponse) GetBody() []byte         { panic("NOIMPL") }
+func (mr *mockResponse) GetContentType() string  { return mr.ctt }
+func (mr *mockResponse) GetCode() int            { panic("NOIMPL") }
+func (mr *mockResponse) GetHeaders() http.Header { return mr.hdr }
 
 func TestNewRequest(t *testing.T) {
 	r := &http.Request{Method: "TESTMETHOD"}
@@ -277,3 +290,133 @@ func TestResponseHandler(t *testing.T) {
 	}
 
 }
+
+func TestResponseHandler_BindRequest(t *testing.T) {
+	mh := &mockHandler{}
+
+	mrt := &mockBindingResponseType{
+		mockResponseType: mockResponseType{t: "first/content-type", resp: &mockResponse{code: 101, body: []byte("unbound")}},
+		bound:            &mockResponseType{t: "first/content-type", resp: &mockResponse{code: 102, body: []byte("bound"), hdr: http.Header{"X-Bound": []string{"yes"}}}},
+	}
+
+	w := headerOnlyResponseWriter{
+		bag: &hoBag{
+			h:        make(http.Header),
+			b:        make([][]byte, 0),
+			whcalled: make([]int, 0),
+		},
+	}
+	r := &http.Request{Header: http.Header{}}
+
+	ResponseHandler(mh.fn, mrt)(w, r, httprouter.Params{})
+
+	ewhc := 102
+	whc := w.bag.whcalled[0]
+
+	if whc != ewhc {
+		t.Errorf("Invalid write header called code, expected %d, got %d", ewhc, whc)
+	}
+
+	eb := "bound"
+	b := string(w.bag.b[0])
+
+	if b != eb {
+		t.Errorf("Invalid body written, expected %s, got %s", eb, b)
+	}
+
+	eh := "yes"
+	h := w.Header().Get("X-Bound")
+
+	if h != eh {
+		t.Errorf("Invalid response header, expected %s, got %s", eh, h)
+	}
+}
+
+func TestResponseHandler_Stream(t *testing.T) {
+	mh := &mockHandler{i: []string{"a", "b"}}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	ResponseHandler(mh.fn, responsetype.TypeNDJSON)(w, r, httprouter.Params{})
+
+	ec := http.StatusOK
+	c := w.Code
+
+	if c != ec {
+		t.Errorf("Invalid status code, expected %d, got %d", ec, c)
+	}
+
+	ect := "application/x-ndjson"
+	ct := w.Header().Get("Content-Type")
+
+	if ct != ect {
+		t.Errorf("Invalid content type, expected %s, got %s", ect, ct)
+	}
+
+	eb := "\"a\"\n\"b\"\n"
+	b := w.Body.String()
+
+	if b != eb {
+		t.Errorf("Invalid streamed body, expected %s, got %s", eb, b)
+	}
+
+	if !w.Flushed {
+		t.Error("Expected streamed response to be flushed")
+	}
+
+	mh.i = nil
+	w = httptest.NewRecorder()
+
+	ResponseHandler(mh.fn, responsetype.TypeNDJSON)(w, r, httprouter.Params{})
+
+	ec = http.StatusNoContent
+	c = w.Code
+
+	if c != ec {
+		t.Errorf("Invalid status code, expected %d, got %d", ec, c)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Expected no content type without content, got %s", ct)
+	}
+}
+
+func TestResponseHandler_AcceptedAliases(t *testing.T) {
+	mh := &mockHandler{i: &responsetype.JSONError{Code: http.StatusNotFound, Description: "testdescription"}}
+
+	fn := ResponseHandler(mh.fn, responsetype.TypeJSON, responsetype.TypeProtobuf)
+
+	for accept, ect := range map[string]string{
+		"application/json":       "application/json",
+		"application/x-protobuf": "application/x-protobuf",
+		"application/protobuf":   "application/protobuf",
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+
+		fn(w, r, httprouter.Params{})
+
+		if ct := w.Header().Get("Content-Type"); ct != ect {
+			t.Errorf("Invalid content type for %s, expected %s, got %s", accept, ect, ct)
+		}
+	}
+}
+
+func TestResponseHandler_InvalidCode(t *testing.T) {
+	for _, resp := range []interface{}{int64(1234567), uint64(1 << 63), int8(10), Respond(1234567, "a")} {
+		fn := ResponseHandler((&mockHandler{i: resp}).fn, responsetype.TypeJSON)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		fn(w, r, httprouter.Params{})
+
+		eb := `{"code":500,"description":"Internal Server Error"}`
+
+		if w.Code != http.StatusInternalServerError || w.Body.String() != eb {
+			t.Errorf("Invalid response for %#v, expected %d %s, got %d %s", resp, http.StatusInternalServerError, eb, w.Code, w.Body.String())
+		}
+	}
+}
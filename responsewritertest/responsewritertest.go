@@ -0,0 +1,221 @@
+// Package responsewritertest invokes response handlers with synthetic requests
+// and asserts on the recorded response.
+package responsewritertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"peterdekok.nl/gotools/responsewriter"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"testing"
+)
+
+// UpdateGolden rewrites golden files with the recorded body instead of comparing,
+// enabled by setting the UPDATE_GOLDEN environment variable.
+var UpdateGolden = len(os.Getenv("UPDATE_GOLDEN")) > 0
+
+// Request describes the synthetic request a handler is invoked with.
+type Request struct {
+	Method string
+	Target string
+	Header http.Header
+	Params httprouter.Params
+	Body   []byte
+}
+
+// Response is the recorded response of a handler.
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+func NewRequest(method, target string) *Request {
+	return &Request{
+		Method: method,
+		Target: target,
+		Header: make(http.Header),
+	}
+}
+
+func (r *Request) WithAccept(accept string) *Request {
+	return r.WithHeader("Accept", accept)
+}
+
+func (r *Request) WithHeader(key, value string) *Request {
+	r.Header.Add(key, value)
+
+	return r
+}
+
+func (r *Request) WithParam(key, value string) *Request {
+	r.Params = append(r.Params, httprouter.Param{Key: key, Value: value})
+
+	return r
+}
+
+func (r *Request) WithBody(body []byte) *Request {
+	r.Body = body
+
+	return r
+}
+
+// WithJSON sets the JSON encoded value as body, panics when it can not be encoded
+func (r *Request) WithJSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		panic(err)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+
+	return r.WithBody(b)
+}
+
+// Invoke serves the request with the handler through ResponseHandler and records the response
+func Invoke(handler responsewriter.Handler, r *Request, preferredType responsewriter.ResponseType, allowedTypes ...responsewriter.ResponseType) *Response {
+	var body io.Reader
+
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+
+	req := httptest.NewRequest(r.Method, r.Target, body)
+
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+
+	responsewriter.ResponseHandler(handler, preferredType, allowedTypes...)(w, req, r.Params)
+
+	res := w.Result()
+	defer func() { _ = res.Body.Close() }()
+
+	b, _ := io.ReadAll(res.Body)
+
+	return &Response{
+		Code:   res.StatusCode,
+		Header: res.Header,
+		Body:   b,
+	}
+}
+
+// DecodeJSON decodes the body into v
+func (r *Response) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+func (r *Response) AssertStatus(t testing.TB, code int) {
+	t.Helper()
+
+	if r.Code != code {
+		t.Errorf("Invalid status code, expected %d, got %d", code, r.Code)
+	}
+}
+
+func (r *Response) AssertHeader(t testing.TB, key, value string) {
+	t.Helper()
+
+	if v := r.Header.Get(key); v != value {
+		t.Errorf("Invalid %s header, expected %s, got %s", key, value, v)
+	}
+}
+
+// AssertJSON compares the body to the expected value semantically, ignoring formatting
+// and key order. A string or byte slice is used as JSON document, other values are encoded.
+func (r *Response) AssertJSON(t testing.TB, expected interface{}) {
+	t.Helper()
+
+	var eb []byte
+
+	switch e := expected.(type) {
+	case string:
+		eb = []byte(e)
+	case []byte:
+		eb = e
+	default:
+		var err error
+
+		if eb, err = json.Marshal(e); err != nil {
+			t.Fatalf("Failed to encode expected value: %v", err)
+		}
+	}
+
+	var ev, gv interface{}
+
+	if err := json.Unmarshal(eb, &ev); err != nil {
+		t.Fatalf("Invalid expected JSON %s: %v", eb, err)
+	}
+
+	if err := json.Unmarshal(r.Body, &gv); err != nil {
+		t.Errorf("Invalid JSON body, expected %s, got %s", eb, r.Body)
+
+		return
+	}
+
+	if !reflect.DeepEqual(ev, gv) {
+		t.Errorf("Invalid JSON body, expected %s, got %s", eb, r.Body)
+	}
+}
+
+// AssertJSONError checks the status code and the JSONError payload of the body
+func (r *Response) AssertJSONError(t testing.TB, code int, description string) {
+	t.Helper()
+
+	r.AssertStatus(t, code)
+
+	je := responsetype.JSONError{}
+
+	if err := r.DecodeJSON(&je); err != nil {
+		t.Errorf("Invalid JSON error body, got %s", r.Body)
+
+		return
+	}
+
+	if je.Code != code {
+		t.Errorf("Invalid JSON error code, expected %d, got %d", code, je.Code)
+	}
+
+	if je.Description != description {
+		t.Errorf("Invalid JSON error description, expected %s, got %v", description, je.Description)
+	}
+}
+
+// AssertGolden compares the body to testdata/<name>.golden, or writes it when UpdateGolden is set
+func (r *Response) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden file directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, r.Body, 0644); err != nil {
+			t.Fatalf("Failed to write golden file %s: %v", path, err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s: %v", path, err)
+	}
+
+	if !bytes.Equal(expected, r.Body) {
+		t.Errorf("Invalid body for golden file %s, expected %s, got %s", path, expected, r.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package responsewritertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"peterdekok.nl/gotools/responsewriter"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+type user struct {
+	ID   int    `json:"id" csv:"id"`
+	Name string `json:"name" csv:"name"`
+}
+
+type recordingTB struct {
+	testing.TB
+
+	failed bool
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func TestInvoke(t *testing.T) {
+	handler := func(r *responsewriter.Request) interface{} {
+		if r.Params.ByName("id") != "1" {
+			return errors.New("not found")
+		}
+
+		return responsewriter.Respond(http.StatusOK, []user{{ID: 1, Name: "a"}}).Header("X-Test", "a")
+	}
+
+	res := Invoke(handler, NewRequest(http.MethodGet, "/users/1").WithParam("id", "1"), responsetype.TypeJSON, responsetype.TypeCSV)
+
+	res.AssertStatus(t, http.StatusOK)
+	res.AssertHeader(t, "Content-Type", "application/json")
+	res.AssertHeader(t, "X-Test", "a")
+	res.AssertJSON(t, `[{"name": "a", "id": 1}]`)
+	res.AssertJSON(t, []user{{ID: 1, Name: "a"}})
+
+	res = Invoke(handler, NewRequest(http.MethodGet, "/users/1").WithParam("id", "1").WithAccept("text/csv"), responsetype.TypeJSON, responsetype.TypeCSV)
+
+	res.AssertGolden(t, "users")
+
+	res = Invoke(handler, NewRequest(http.MethodGet, "/users/2"), responsetype.TypeJSON)
+
+	res.AssertJSONError(t, http.StatusInternalServerError, "Internal Server Error")
+}
+
+func TestInvoke_Body(t *testing.T) {
+	handler := responsewriter.Typed(func(_ context.Context, _ *responsewriter.Request, in user) (user, error) {
+		return in, nil
+	})
+
+	res := Invoke(handler, NewRequest(http.MethodPost, "/users").WithJSON(user{ID: 2, Name: "b"}), responsetype.TypeJSON)
+
+	res.AssertStatus(t, http.StatusOK)
+	res.AssertJSON(t, `{"id":2,"name":"b"}`)
+
+	res = Invoke(handler, NewRequest(http.MethodPost, "/users").WithBody([]byte("{")), responsetype.TypeJSON)
+
+	res.AssertJSONError(t, http.StatusBadRequest, "Invalid request body")
+}
+
+func TestResponse_AssertFailures(t *testing.T) {
+	res := &Response{Code: http.StatusOK, Header: http.Header{}, Body: []byte(`{"code":400,"description":"a"}`)}
+
+	tests := map[string]func(tb testing.TB){
+		"status":     func(tb testing.TB) { res.AssertStatus(tb, http.StatusCreated) },
+		"header":     func(tb testing.TB) { res.AssertHeader(tb, "X-Test", "a") },
+		"json":       func(tb testing.TB) { res.AssertJSON(tb, `{"code":401}`) },
+		"json error": func(tb testing.TB) { res.AssertJSONError(tb, http.StatusOK, "b") },
+		"golden":     func(tb testing.TB) { res.AssertGolden(tb, "users") },
+	}
+
+	for name, fn := range tests {
+		tb := &recordingTB{TB: t}
+
+		fn(tb)
+
+		if !tb.failed {
+			t.Errorf("Expected %s assertion to fail", name)
+		}
+	}
+}
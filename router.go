@@ -0,0 +1,268 @@
+package responsewriter
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"sync"
+)
+
+// Middleware wraps a handler, e.g. to check the request before calling the handler.
+type Middleware func(Handler) Handler
+
+// Router registers handlers with httprouter using shared response types and middleware.
+//
+// Groups share the underlying httprouter, but have their own prefix, middleware and types.
+// Unknown routes and methods are rendered through the negotiated response type, with the
+// types, middleware and writer of the root router.
+type Router struct {
+	router     *httprouter.Router
+	fallbacks  *fallbacks
+	preflights *preflights
+
+	prefix     string
+	middleware []Middleware
+	types      []ResponseType
+	registry   *Registry
+	writer     *Writer
+	cors       *CORS
+}
+
+// NewRouter creates a router serving routes with the given default response types
+func NewRouter(preferredType ResponseType, allowedTypes ...ResponseType) *Router {
+	if preferredType == nil {
+		panic("Invalid response type given for router")
+	}
+
+	rt := &Router{
+		router:     httprouter.New(),
+		fallbacks:  &fallbacks{},
+		preflights: &preflights{},
+		types:      append([]ResponseType{preferredType}, allowedTypes...),
+		writer:     defaultWriter,
+	}
+
+	rt.fallbacks.root = rt
+
+	rt.NotFound(func(r *Request) interface{} {
+		return errorReply(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil)
+	})
+
+	rt.MethodNotAllowed(func(r *Request) interface{} {
+		return errorReply(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), nil)
+	})
+
+	return rt
+}
+
+// HTTPRouter returns the underlying httprouter
+func (rt *Router) HTTPRouter() *httprouter.Router {
+	return rt.router
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.router.ServeHTTP(w, r)
+}
+
+// Group returns a router registering routes below the prefix,
+// inheriting the middleware and types of this router
+func (rt *Router) Group(prefix string) *Router {
+	g := rt.clone()
+	g.prefix = rt.prefix + prefix
+
+	return g
+}
+
+// Use adds middleware to routes registered afterwards, the first middleware is the outermost.
+// Middleware of the root router applies to unknown routes as well.
+func (rt *Router) Use(middleware ...Middleware) *Router {
+	rt.fallbacks.update(rt, func() {
+		rt.middleware = append(rt.middleware[:len(rt.middleware):len(rt.middleware)], middleware...)
+	})
+
+	return rt
+}
+
+// WithTypes returns a router sharing routes, serving with different response types
+func (rt *Router) WithTypes(preferredType ResponseType, allowedTypes ...ResponseType) *Router {
+	if preferredType == nil {
+		panic("Invalid response type given for router")
+	}
+
+	c := rt.clone()
+	c.types = append([]ResponseType{preferredType}, allowedTypes...)
+
+	return c
+}
+
+// WithRegistry returns a router recording its routes in the registry
+func (rt *Router) WithRegistry(registry *Registry) *Router {
+	c := rt.clone()
+	c.registry = registry
+
+	return c
+}
+
+// WithWriter returns a router creating the handlers of its routes with the writer, e.g. to collect
+// metrics labelled with the route path. Set on the root router, the returned router becomes the
+// root and the writer applies to unknown routes as well.
+func (rt *Router) WithWriter(writer *Writer) *Router {
+	c := rt.clone()
+	c.writer = writer
+
+	rt.fallbacks.update(rt, func() {
+		if rt.fallbacks.root == rt {
+			rt.fallbacks.root = c
+		}
+	})
+
+	return c
+}
+
+func (rt *Router) GET(path string, handler Handler, middleware ...Middleware) {
+	rt.Handle(Route{Method: http.MethodGet, Path: path, Handler: handler, Middleware: middleware})
+}
+
+func (rt *Router) POST(path string, handler Handler, middleware ...Middleware) {
+	rt.Handle(Route{Method: http.MethodPost, Path: path, Handler: handler, Middleware: middleware})
+}
+
+func (rt *Router) PUT(path string, handler Handler, middleware ...Middleware) {
+	rt.Handle(Route{Method: http.MethodPut, Path: path, Handler: handler, Middleware: middleware})
+}
+
+func (rt *Router) PATCH(path string, handler Handler, middleware ...Middleware) {
+	rt.Handle(Route{Method: http.MethodPatch, Path: path, Handler: handler, Middleware: middleware})
+}
+
+func (rt *Router) DELETE(path string, handler Handler, middleware ...Middleware) {
+	rt.Handle(Route{Method: http.MethodDelete, Path: path, Handler: handler, Middleware: middleware})
+}
+
+// Handle registers the route below the prefix of the router, using the router's types
+// when the route has none. The middleware of the route runs after the router's middleware.
+// The route is recorded in the registry, if any.
+func (rt *Router) Handle(route Route) {
+	route.Path = rt.prefix + route.Path
+	route.Handler = rt.wrap(wrap(route.Handler, route.Middleware))
+
+	if len(route.Types) == 0 {
+		route.Types = rt.types
+	}
+
+	if rt.registry != nil {
+		rt.registry.record(route)
+	}
+
+	wr := rt.handlerWriter().WithRoute(route.Path)
+
+	rt.router.Handle(route.Method, route.Path, wr.Handler(route.Handler, route.Types[0], route.Types[1:]...))
+
+	if rt.cors != nil && route.Method != http.MethodOptions {
+		rt.preflights.add(route.Path, wr, route.Types)
+	}
+}
+
+// handlerWriter returns the writer of the router, adding the CORS headers when configured
+func (rt *Router) handlerWriter() *Writer {
+	if rt.cors == nil {
+		return rt.writer
+	}
+
+	c := *rt.writer
+	c.cors = rt.cors
+
+	return &c
+}
+
+// NotFound sets the handler for unknown routes
+func (rt *Router) NotFound(handler Handler) {
+	rt.router.NotFound = &fallbackHandler{fallbacks: rt.fallbacks, handler: handler}
+}
+
+// MethodNotAllowed sets the handler for known routes requested with another method,
+// the Allow header is set by httprouter
+func (rt *Router) MethodNotAllowed(handler Handler) {
+	rt.router.MethodNotAllowed = &fallbackHandler{fallbacks: rt.fallbacks, handler: handler}
+}
+
+// fallbacks holds the root router, whose configuration applies to the handlers of unknown routes
+type fallbacks struct {
+	mu   sync.RWMutex
+	root *Router
+	// version changes with the configuration of the root, invalidating the responders
+	version int
+}
+
+// update changes the configuration of the router, invalidating the responders when it is the root
+func (fb *fallbacks) update(rt *Router, change func()) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	root := fb.root == rt
+
+	change()
+
+	if root {
+		fb.version++
+	}
+}
+
+// fallbackHandler serves a handler outside the routes, e.g. for unknown routes
+type fallbackHandler struct {
+	fallbacks *fallbacks
+	handler   Handler
+
+	rs      *responder
+	version int
+}
+
+func (fh *fallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fh.responder().serve(w, NewRequest(r, nil))
+}
+
+// responder returns the responder of the handler, built again after the root changed
+func (fh *fallbackHandler) responder() *responder {
+	fb := fh.fallbacks
+
+	fb.mu.RLock()
+
+	if rs := fh.rs; rs != nil && fh.version == fb.version {
+		fb.mu.RUnlock()
+
+		return rs
+	}
+
+	fb.mu.RUnlock()
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if fh.rs == nil || fh.version != fb.version {
+		root := fb.root
+
+		fh.rs = root.handlerWriter().responder(root.wrap(fh.handler), root.types[0], root.types[1:]...)
+		fh.version = fb.version
+	}
+
+	return fh.rs
+}
+
+func (rt *Router) wrap(handler Handler) Handler {
+	return wrap(handler, rt.middleware)
+}
+
+// wrap applies the middleware to the handler, the first middleware is the outermost
+func wrap(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+func (rt *Router) clone() *Router {
+	c := *rt
+	c.middleware = rt.middleware[:len(rt.middleware):len(rt.middleware)]
+
+	return &c
+}
@@ -0,0 +1,226 @@
+package responsewriter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+func serveRouter(rt *Router, method, path, accept string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+
+	if len(accept) > 0 {
+		r.Header.Set("Accept", accept)
+	}
+
+	rt.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRouter_Methods(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON)
+
+	handler := func(r *Request) interface{} {
+		return r.Method + " " + r.Params.ByName("id")
+	}
+
+	rt.GET("/a/:id", handler)
+	rt.POST("/a/:id", handler)
+	rt.PUT("/a/:id", handler)
+	rt.PATCH("/a/:id", handler)
+	rt.DELETE("/a/:id", handler)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		w := serveRouter(rt, method, "/a/1", "")
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Invalid status code for %s, expected %d, got %d", method, http.StatusOK, w.Code)
+		}
+
+		if b := w.Body.String(); b != method+" 1" {
+			t.Errorf("Invalid body for %s, expected %s, got %s", method, method+" 1", b)
+		}
+	}
+}
+
+func TestRouter_Group(t *testing.T) {
+	var calls []string
+
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(r *Request) interface{} {
+				calls = append(calls, name)
+
+				return next(r)
+			}
+		}
+	}
+
+	rt := NewRouter(responsetype.TypeJSON).Use(mw("root"))
+
+	api := rt.Group("/api").Use(mw("api"))
+	v1 := api.Group("/v1").WithTypes(responsetype.TypeCSV)
+
+	rt.GET("/", func(r *Request) interface{} { return []int{1} })
+	v1.GET("/items", func(r *Request) interface{} { return []int{1} })
+
+	w := serveRouter(rt, http.MethodGet, "/api/v1/items", "")
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Invalid content type, expected %s, got %s", "text/csv", ct)
+	}
+
+	if len(calls) != 2 || calls[0] != "root" || calls[1] != "api" {
+		t.Errorf("Invalid middleware calls, expected [root api], got %v", calls)
+	}
+
+	calls = nil
+
+	w = serveRouter(rt, http.MethodGet, "/", "")
+
+	if b := w.Body.String(); b != "[1]" {
+		t.Errorf("Invalid body, expected %s, got %s", "[1]", b)
+	}
+
+	if len(calls) != 1 || calls[0] != "root" {
+		t.Errorf("Invalid middleware calls, expected [root], got %v", calls)
+	}
+}
+
+func TestRouter_NotFound(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON, responsetype.TypeCSV)
+
+	rt.GET("/a", func(r *Request) interface{} { return nil })
+
+	w := serveRouter(rt, http.MethodGet, "/b", "")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if b := w.Body.String(); b != `{"code":404,"description":"Not Found"}` {
+		t.Errorf("Invalid body, expected %s, got %s", `{"code":404,"description":"Not Found"}`, b)
+	}
+
+	w = serveRouter(rt, http.MethodGet, "/b", "text/csv")
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Invalid content type, expected %s, got %s", "text/csv", ct)
+	}
+
+	w = serveRouter(rt, http.MethodPost, "/a", "")
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	if a := w.Header().Get("Allow"); a != "GET, OPTIONS" {
+		t.Errorf("Invalid allow header, expected %s, got %s", "GET, OPTIONS", a)
+	}
+
+	if b := w.Body.String(); b != `{"code":405,"description":"Method Not Allowed"}` {
+		t.Errorf("Invalid body, expected %s, got %s", `{"code":405,"description":"Method Not Allowed"}`, b)
+	}
+
+	rt.NotFound(func(r *Request) interface{} { return Respond(http.StatusNotFound, "gone") })
+
+	w = serveRouter(rt, http.MethodGet, "/b", "")
+
+	if b := w.Body.String(); b != "gone" {
+		t.Errorf("Invalid body, expected %s, got %s", "gone", b)
+	}
+}
+
+func TestRouter_NotFoundResponder(t *testing.T) {
+	rt := NewRouter(responsetype.TypeJSON)
+
+	fh := rt.router.NotFound.(*fallbackHandler)
+
+	serveRouter(rt, http.MethodGet, "/a", "")
+
+	rs := fh.rs
+
+	serveRouter(rt, http.MethodGet, "/b", "")
+
+	if fh.rs != rs {
+		t.Errorf("Expected the not found responder to be reused")
+	}
+
+	rt.Group("/g").Use(func(next Handler) Handler { return next })
+
+	if serveRouter(rt, http.MethodGet, "/c", ""); fh.rs != rs {
+		t.Errorf("Expected group middleware not to rebuild the not found responder")
+	}
+
+	rt.Use(func(next Handler) Handler {
+		return func(r *Request) interface{} { return Respond(http.StatusNotFound, "middleware") }
+	})
+
+	if w := serveRouter(rt, http.MethodGet, "/d", ""); w.Body.String() != "middleware" || fh.rs == rs {
+		t.Errorf("Expected the not found responder to be rebuilt with the middleware, got %s", w.Body.String())
+	}
+}
+
+func TestRouter_Registry(t *testing.T) {
+	reg := NewRegistry("Test", "1.0.0")
+
+	rt := NewRouter(responsetype.TypeJSON).WithRegistry(reg).Group("/api")
+
+	rt.Handle(Route{
+		Method:   http.MethodGet,
+		Path:     "/users/:id",
+		Handler:  func(r *Request) interface{} { return []string{"a"} },
+		Response: []string{},
+	})
+
+	op := reg.Document().Paths["/api/users/{id}"]["get"]
+
+	if op == nil {
+		t.Fatalf("Expected get operation on /api/users/{id}")
+	}
+
+	if _, ok := op.Responses["200"].Content["application/json"]; !ok {
+		t.Errorf("Expected application/json response content, got %#v", op.Responses["200"].Content)
+	}
+
+	w := serveRouter(rt, http.MethodGet, "/api/users/1", "")
+
+	if b := w.Body.String(); b != `["a"]` {
+		t.Errorf("Invalid body, expected %s, got %s", `["a"]`, b)
+	}
+}
+
+func TestRouter_GroupWithWriter(t *testing.T) {
+	m := &metricsMock{}
+
+	rt := NewRouter(responsetype.TypeJSON)
+
+	serveRouter(rt, http.MethodGet, "/built", "")
+
+	admin := rt.Group("/admin").Use(func(next Handler) Handler {
+		return func(r *Request) interface{} { return Status(http.StatusUnauthorized) }
+	}).WithWriter(NewWriter().WithMetrics(m))
+
+	admin.GET("/users", func(r *Request) interface{} { return nil })
+
+	if w := serveRouter(rt, http.MethodGet, "/unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Invalid status code for an unknown route, expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if w := serveRouter(rt, http.MethodGet, "/admin/users", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Invalid status code for the group, expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	if len(m.observations) != 1 {
+		t.Errorf("Expected only the group route to be observed, got %d", len(m.observations))
+	}
+
+	root := rt.WithWriter(NewWriter().WithMetrics(m))
+
+	if w := serveRouter(root, http.MethodGet, "/unknown", ""); w.Code != http.StatusNotFound || len(m.observations) != 2 {
+		t.Errorf("Expected the rebuilt not found responder to use the root writer, got %d with %d observations", w.Code, len(m.observations))
+	}
+}
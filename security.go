@@ -0,0 +1,127 @@
+package responsewriter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityHeaders is a profile of security related response headers.
+// Headers already set by the handler or response are left untouched.
+type SecurityHeaders struct {
+	// NoSniff sets X-Content-Type-Options: nosniff
+	NoSniff bool
+	// NoStoreErrors sets Cache-Control: no-store on 4xx and 5xx responses
+	NoStoreErrors bool
+	// ContentSecurityPolicy is set on HTML responses
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+	// HSTSMaxAge sets Strict-Transport-Security on requests over TLS, when positive
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+}
+
+// DefaultSecurityHeaders returns a strict profile, suitable for APIs and server rendered pages
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		NoSniff:               true,
+		NoStoreErrors:         true,
+		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+	}
+}
+
+// WithSecurityHeaders returns a writer setting the security headers on every response.
+// Use a writer with another profile to override it per route.
+func (wr *Writer) WithSecurityHeaders(headers SecurityHeaders) *Writer {
+	c := *wr
+	c.security = &headers
+
+	return &c
+}
+
+// WithoutSecurityHeaders returns a writer not setting security headers
+func (wr *Writer) WithoutSecurityHeaders() *Writer {
+	c := *wr
+	c.security = nil
+
+	return &c
+}
+
+func (sh *SecurityHeaders) apply(h http.Header, code int, tls bool) {
+	if sh.NoSniff {
+		setDefault(h, "X-Content-Type-Options", "nosniff")
+	}
+
+	if sh.NoStoreErrors && code >= http.StatusBadRequest {
+		setDefault(h, "Cache-Control", "no-store")
+	}
+
+	if len(sh.ContentSecurityPolicy) > 0 && isHTML(h.Get("Content-Type")) {
+		setDefault(h, "Content-Security-Policy", sh.ContentSecurityPolicy)
+	}
+
+	if len(sh.ReferrerPolicy) > 0 {
+		setDefault(h, "Referrer-Policy", sh.ReferrerPolicy)
+	}
+
+	if sh.HSTSMaxAge > 0 && tls {
+		v := "max-age=" + strconv.Itoa(int(sh.HSTSMaxAge.Seconds()))
+
+		if sh.HSTSIncludeSubdomains {
+			v += "; includeSubDomains"
+		}
+
+		if sh.HSTSPreload {
+			v += "; preload"
+		}
+
+		setDefault(h, "Strict-Transport-Security", v)
+	}
+}
+
+// securityWriter applies the security headers once the status code is known
+type securityWriter struct {
+	http.ResponseWriter
+
+	headers *SecurityHeaders
+	tls     bool
+	written bool
+}
+
+func (sw *securityWriter) WriteHeader(code int) {
+	if !sw.written {
+		sw.written = true
+		sw.headers.apply(sw.Header(), code, sw.tls)
+	}
+
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *securityWriter) Write(b []byte) (int, error) {
+	if !sw.written {
+		sw.WriteHeader(http.StatusOK)
+	}
+
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *securityWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func setDefault(h http.Header, key, value string) {
+	if len(h.Get(key)) == 0 {
+		h.Set(key, value)
+	}
+}
+
+func isHTML(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(contentType), "text/html")
+}
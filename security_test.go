@@ -0,0 +1,93 @@
+package responsewriter
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+func TestWriter_SecurityHeaders(t *testing.T) {
+	wr := NewWriter().WithSecurityHeaders(DefaultSecurityHeaders())
+
+	tests := []struct {
+		name     string
+		resp     interface{}
+		tls      bool
+		expected map[string]string
+	}{
+		{"ok", []int{1}, false, map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"Referrer-Policy":           "strict-origin-when-cross-origin",
+			"Cache-Control":             "",
+			"Content-Security-Policy":   "",
+			"Strict-Transport-Security": "",
+		}},
+		{"error", errors.New("testerror"), true, map[string]string{
+			"Cache-Control":             "no-store",
+			"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		}},
+		{"html", errors.New("testerror"), false, map[string]string{
+			"Content-Security-Policy": "default-src 'self'; frame-ancestors 'none'",
+		}},
+		{"override", Status(http.StatusNotFound).Header("Cache-Control", "max-age=60"), false, map[string]string{
+			"Cache-Control": "max-age=60",
+		}},
+	}
+
+	for _, test := range tests {
+		resp := test.resp
+
+		var rt ResponseType = responsetype.TypeJSON
+
+		if test.name == "html" {
+			rt = responsetype.NewHTMLType(responsetype.NewHTMLTemplates(t.TempDir()))
+		}
+
+		fn := wr.Handler(func(r *Request) interface{} { return resp }, rt)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+
+		fn(w, r, httprouter.Params{})
+
+		for k, v := range test.expected {
+			if h := w.Header().Get(k); h != v {
+				t.Errorf("Invalid %s header for %s, expected %q, got %q", k, test.name, v, h)
+			}
+		}
+	}
+
+	fn := wr.WithoutSecurityHeaders().Handler(func(r *Request) interface{} { return nil }, responsetype.TypeJSON)
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest(http.MethodGet, "/", nil), httprouter.Params{})
+
+	if h := w.Header().Get("X-Content-Type-Options"); h != "" {
+		t.Errorf("Expected no security headers, got %s", h)
+	}
+}
+
+func TestWriter_SecurityHeadersStream(t *testing.T) {
+	fn := NewWriter().WithSecurityHeaders(SecurityHeaders{NoSniff: true}).Handler(func(r *Request) interface{} {
+		return []int{1, 2}
+	}, responsetype.TypeNDJSON)
+
+	w := httptest.NewRecorder()
+	fn(w, httptest.NewRequest(http.MethodGet, "/", nil), httprouter.Params{})
+
+	if h := w.Header().Get("X-Content-Type-Options"); h != "nosniff" {
+		t.Errorf("Invalid nosniff header on stream, expected %s, got %s", "nosniff", h)
+	}
+
+	if !w.Flushed {
+		t.Errorf("Expected the stream to be flushed")
+	}
+}
@@ -0,0 +1,98 @@
+package responsewriter
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Span attributes recorded by the Writer, following the OpenTelemetry HTTP semantic conventions
+// where one exists
+const (
+	AttributeMethod     = attribute.Key("http.request.method")
+	AttributeRoute      = attribute.Key("http.route")
+	AttributePath       = attribute.Key("url.path")
+	AttributeStatusCode = attribute.Key("http.response.status_code")
+	AttributeBodySize   = attribute.Key("http.response.body.size")
+	AttributeMediaType  = attribute.Key("responsewriter.media_type")
+	AttributeFallback   = attribute.Key("responsewriter.fallback")
+)
+
+// WithTracer returns a writer starting a server span for every request. The trace context of
+// the incoming request is extracted with the propagator, W3C trace context by default.
+// The context of the Request passed to the handler carries the span.
+func (wr *Writer) WithTracer(tracer trace.Tracer) *Writer {
+	c := *wr
+	c.tracer = tracer
+
+	if c.propagator == nil {
+		c.propagator = propagation.TraceContext{}
+	}
+
+	return &c
+}
+
+// WithPropagator returns a writer extracting the trace context with the propagator
+func (wr *Writer) WithPropagator(propagator propagation.TextMapPropagator) *Writer {
+	c := *wr
+	c.propagator = propagator
+
+	return &c
+}
+
+// startSpan starts the span of the request when tracing, replacing the request context
+func (wr *Writer) startSpan(r *Request) trace.Span {
+	if wr.tracer == nil {
+		return nil
+	}
+
+	ctx := wr.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	name := r.Method
+
+	if len(wr.route) > 0 {
+		name += " " + wr.route
+	}
+
+	attrs := []attribute.KeyValue{
+		AttributeMethod.String(r.Method),
+		AttributePath.String(r.URL.Path),
+	}
+
+	if len(wr.route) > 0 {
+		attrs = append(attrs, AttributeRoute.String(wr.route))
+	}
+
+	ctx, span := wr.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+
+	r.Request = r.Request.WithContext(ctx)
+
+	return span
+}
+
+// endSpan records the outcome of the response and ends the span,
+// server errors mark the span as failed
+func endSpan(span trace.Span, o *outcome) {
+	span.SetAttributes(
+		AttributeStatusCode.Int(o.code),
+		AttributeBodySize.Int(o.size),
+		AttributeMediaType.String(o.t.GetAcceptedType()),
+	)
+
+	if o.fallback {
+		span.SetAttributes(AttributeFallback.Bool(true))
+		span.AddEvent("Unrecognized response, serving default error")
+	}
+
+	if o.err != nil {
+		span.RecordError(o.err)
+	}
+
+	if o.code >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(o.code))
+	}
+
+	span.End()
+}
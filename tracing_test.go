@@ -0,0 +1,131 @@
+package responsewriter
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"testing"
+)
+
+func spanAttributes(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func TestWriter_Tracer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	wr := NewWriter().WithTracer(tp.Tracer("test")).WithRoute("/users/:id")
+
+	var handlerSpan trace.SpanContext
+
+	err := errors.New("testerror")
+
+	fn := wr.Handler(func(r *Request) interface{} {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+
+		return responsetype.NewJSON(http.StatusBadGateway, "failed").WithError(err)
+	}, responsetype.TypeJSON)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	fn(w, r, httprouter.Params{})
+
+	spans := exporter.GetSpans().Snapshots()
+
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	s := spans[0]
+
+	if s.Name() != "GET /users/:id" {
+		t.Errorf("Invalid span name, expected %s, got %s", "GET /users/:id", s.Name())
+	}
+
+	if s.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Invalid span kind, expected %s, got %s", trace.SpanKindServer, s.SpanKind())
+	}
+
+	if tid := s.SpanContext().TraceID().String(); tid != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("Invalid trace id, expected the propagated trace, got %s", tid)
+	}
+
+	if p := s.Parent().SpanID().String(); p != "b7ad6b7169203331" {
+		t.Errorf("Invalid parent span id, expected %s, got %s", "b7ad6b7169203331", p)
+	}
+
+	if handlerSpan.SpanID() != s.SpanContext().SpanID() {
+		t.Errorf("Expected the request context to carry the span")
+	}
+
+	attrs := spanAttributes(s)
+
+	if c := attrs[AttributeStatusCode].AsInt64(); c != http.StatusBadGateway {
+		t.Errorf("Invalid status code attribute, expected %d, got %d", http.StatusBadGateway, c)
+	}
+
+	if mt := attrs[AttributeMediaType].AsString(); mt != "application/json" {
+		t.Errorf("Invalid media type attribute, expected %s, got %s", "application/json", mt)
+	}
+
+	if size := attrs[AttributeBodySize].AsInt64(); size != int64(w.Body.Len()) {
+		t.Errorf("Invalid body size attribute, expected %d, got %d", w.Body.Len(), size)
+	}
+
+	if rt := attrs[AttributeRoute].AsString(); rt != "/users/:id" {
+		t.Errorf("Invalid route attribute, expected %s, got %s", "/users/:id", rt)
+	}
+
+	if s.Status().Code != codes.Error {
+		t.Errorf("Invalid span status, expected %s, got %s", codes.Error, s.Status().Code)
+	}
+
+	if len(s.Events()) != 1 || s.Events()[0].Name != "exception" {
+		t.Errorf("Expected an exception event, got %#v", s.Events())
+	}
+}
+
+func TestWriter_TracerFallback(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	fn := NewWriter().WithTracer(tp.Tracer("test")).Handler(func(r *Request) interface{} {
+		return make(chan int)
+	}, responsetype.TypeJSON)
+
+	fn(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), httprouter.Params{})
+
+	spans := exporter.GetSpans().Snapshots()
+
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	if n := spans[0].Name(); n != http.MethodPost {
+		t.Errorf("Invalid span name, expected %s, got %s", http.MethodPost, n)
+	}
+
+	if !spanAttributes(spans[0])[AttributeFallback].AsBool() {
+		t.Errorf("Expected fallback attribute")
+	}
+
+	if spans[0].Parent().IsValid() {
+		t.Errorf("Expected a root span without trace context")
+	}
+}
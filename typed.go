@@ -0,0 +1,118 @@
+package responsewriter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"reflect"
+	"strings"
+)
+
+// NoBody is used as input type of typed handlers which do not read the request body.
+type NoBody struct{}
+
+// Validator is implemented by input types checking themselves after decoding,
+// a failure is answered with 422 Unprocessable Entity.
+type Validator interface {
+	Validate() error
+}
+
+// Typed creates a Handler from a handler with typed input and output.
+//
+// The request body is decoded as JSON into In, unless In is NoBody. An empty body
+// leaves In at its zero value. A body which can not be decoded is answered with 400,
+// an unsupported content type with 415. Out is served by the negotiated response type
+// as body with 200, so a string or integer Out is encoded instead of being taken as
+// pre-encoded body or status code. A Reply, Result or Coder still sets the status code,
+// an interface Out follows the same rules as any other return value. A returned error
+// is served as error instead of Out.
+func Typed[In, Out any](fn func(ctx context.Context, r *Request, in In) (Out, error)) Handler {
+	return func(r *Request) interface{} {
+		var in In
+
+		if _, noBody := any(in).(NoBody); !noBody {
+			if reply := decodeBody(r, &in); reply != nil {
+				return reply
+			}
+		}
+
+		out, err := fn(r.Context(), r, in)
+
+		if err != nil {
+			return err
+		}
+
+		return typedBody(out)
+	}
+}
+
+// typedString and typedBytes are encoded by the response type, unlike string and []byte
+type typedString string
+
+type typedBytes []byte
+
+// typedBody wraps the values Classify does not serve as encoded body,
+// unless Out is an interface and the handler chooses the rules itself
+func typedBody[Out any](out Out) interface{} {
+	if reflect.TypeOf((*Out)(nil)).Elem().Kind() == reflect.Interface {
+		return out
+	}
+
+	switch o := any(out).(type) {
+	case string:
+		return Respond(http.StatusOK, typedString(o))
+	case []byte:
+		return Respond(http.StatusOK, typedBytes(o))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return Respond(http.StatusOK, o)
+	}
+
+	return out
+}
+
+// decodeBody decodes the JSON request body, returning a reply when the request is invalid
+func decodeBody(r *Request, v interface{}) *Reply {
+	if r.Body == nil || r.Body == http.NoBody {
+		return validate(v)
+	}
+
+	if ct := r.Header.Get("Content-Type"); len(ct) > 0 {
+		mt, _, err := mime.ParseMediaType(ct)
+
+		if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+			return errorReply(http.StatusUnsupportedMediaType, "Unsupported content type, expected application/json", err)
+		}
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return errorReply(http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	return validate(v)
+}
+
+func validate(v interface{}) *Reply {
+	val, ok := v.(Validator)
+
+	if !ok {
+		return nil
+	}
+
+	if err := val.Validate(); err != nil {
+		return errorReply(http.StatusUnprocessableEntity, err.Error(), err)
+	}
+
+	return nil
+}
+
+func errorReply(code int, description string, err error) *Reply {
+	return Status(code).Body(responsetype.JSONError{
+		Code:        code,
+		Description: description,
+		Err:         err,
+	}).WithError(err)
+}
@@ -0,0 +1,149 @@
+package responsewriter
+
+import (
+	"context"
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/http/httptest"
+	"peterdekok.nl/gotools/responsewriter/responsetype"
+	"strings"
+	"testing"
+)
+
+type typedIn struct {
+	Name string `json:"name"`
+}
+
+func (i typedIn) Validate() error {
+	if i.Name == "invalid" {
+		return errors.New("name is invalid")
+	}
+
+	return nil
+}
+
+type typedOut struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(_ context.Context, _ *Request, in typedIn) (*typedOut, error) {
+	if in.Name == "fail" {
+		return nil, errors.New("testerror")
+	}
+
+	return &typedOut{Greeting: "Hello " + in.Name}, nil
+}
+
+func serveTyped(h Handler, body, contentType string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Accept", "application/json")
+
+	if len(contentType) > 0 {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	ResponseHandler(h, responsetype.TypeJSON)(w, r, httprouter.Params{})
+
+	return w
+}
+
+func TestTyped(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		code        int
+		expected    string
+	}{
+		{"decoded", `{"name":"world"}`, "application/json; charset=utf-8", http.StatusOK, `{"greeting":"Hello world"}`},
+		{"suffix content type", `{"name":"world"}`, "application/vnd.test+json", http.StatusOK, `{"greeting":"Hello world"}`},
+		{"no content type", `{"name":"world"}`, "", http.StatusOK, `{"greeting":"Hello world"}`},
+		{"empty body", "", "application/json", http.StatusOK, `{"greeting":"Hello "}`},
+		{"invalid body", `{"name":`, "application/json", http.StatusBadRequest, `{"code":400,"description":"Invalid request body"}`},
+		{"unsupported content type", `name=world`, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType, `{"code":415,"description":"Unsupported content type, expected application/json"}`},
+		{"validation", `{"name":"invalid"}`, "application/json", http.StatusUnprocessableEntity, `{"code":422,"description":"name is invalid"}`},
+		{"handler error", `{"name":"fail"}`, "application/json", http.StatusInternalServerError, `{"code":500,"description":"Internal Server Error"}`},
+	}
+
+	for _, test := range tests {
+		w := serveTyped(Typed(greet), test.body, test.contentType)
+
+		if w.Code != test.code {
+			t.Errorf("Invalid status code for %s, expected %d, got %d", test.name, test.code, w.Code)
+		}
+
+		if b := w.Body.String(); b != test.expected {
+			t.Errorf("Invalid body for %s, expected %s, got %s", test.name, test.expected, b)
+		}
+	}
+}
+
+func TestTyped_NoBody(t *testing.T) {
+	fn := Typed(func(_ context.Context, _ *Request, _ NoBody) ([]int, error) {
+		return []int{1, 2}, nil
+	})
+
+	w := serveTyped(fn, `not json`, "text/plain")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if b := w.Body.String(); b != "[1,2]" {
+		t.Errorf("Invalid body, expected %s, got %s", "[1,2]", b)
+	}
+}
+
+func TestTyped_Reply(t *testing.T) {
+	fn := Typed(func(_ context.Context, _ *Request, in typedIn) (*Reply, error) {
+		return Respond(http.StatusCreated, typedOut{Greeting: in.Name}).Header("Location", "/"+in.Name), nil
+	})
+
+	w := serveTyped(fn, `{"name":"a"}`, "application/json")
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	if l := w.Header().Get("Location"); l != "/a" {
+		t.Errorf("Invalid location header, expected %s, got %s", "/a", l)
+	}
+}
+
+func TestTyped_Scalar(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  Handler
+		expected string
+	}{
+		{"int", Typed(func(_ context.Context, _ *Request, _ NoBody) (int, error) { return 42, nil }), "42"},
+		{"uint16", Typed(func(_ context.Context, _ *Request, _ NoBody) (uint16, error) { return 404, nil }), "404"},
+		{"string", Typed(func(_ context.Context, _ *Request, _ NoBody) (string, error) { return "hello", nil }), `"hello"`},
+		{"empty string", Typed(func(_ context.Context, _ *Request, _ NoBody) (string, error) { return "", nil }), `""`},
+		{"bytes", Typed(func(_ context.Context, _ *Request, _ NoBody) ([]byte, error) { return []byte("hi"), nil }), `"aGk="`},
+	}
+
+	for _, test := range tests {
+		w := serveTyped(test.handler, "", "")
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Invalid status code for %s, expected %d, got %d", test.name, http.StatusOK, w.Code)
+		}
+
+		if b := w.Body.String(); b != test.expected {
+			t.Errorf("Invalid body for %s, expected %s, got %s", test.name, test.expected, b)
+		}
+	}
+}
+
+func TestTyped_Interface(t *testing.T) {
+	fn := Typed(func(_ context.Context, _ *Request, _ NoBody) (interface{}, error) {
+		return http.StatusAccepted, nil
+	})
+
+	if w := serveTyped(fn, "", ""); w.Code != http.StatusAccepted {
+		t.Errorf("Invalid status code, expected %d, got %d", http.StatusAccepted, w.Code)
+	}
+}
@@ -0,0 +1,216 @@
+package responsewriter
+
+import (
+	"bytes"
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Writer creates handlers sharing the same configuration, e.g. metrics.
+//
+// The With methods return a copy, so a Writer can be shared and specialized safely.
+type Writer struct {
+	metrics    Metrics
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	requestID  func() string
+	security   *SecurityHeaders
+	cors       *CORS
+	route      string
+}
+
+// defaultWriter is used by ResponseHandler and the other package level adapters
+var defaultWriter = NewWriter()
+
+// SetDefaultWriter sets the writer used by ResponseHandler and the other package level adapters,
+// handlers created before are not affected
+func SetDefaultWriter(writer *Writer) {
+	if writer == nil {
+		writer = NewWriter()
+	}
+
+	defaultWriter = writer
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// WithMetrics returns a writer reporting every response to the metrics
+func (wr *Writer) WithMetrics(metrics Metrics) *Writer {
+	c := *wr
+	c.metrics = metrics
+
+	return &c
+}
+
+// WithRoute returns a writer labelling the metrics of its handlers with the route
+func (wr *Writer) WithRoute(route string) *Writer {
+	c := *wr
+	c.route = route
+
+	return &c
+}
+
+// Handler serves the return value of the handler with the negotiated response type
+func (wr *Writer) Handler(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) httprouter.Handle {
+	rs := wr.responder(handler, preferredType, allowedTypes...)
+
+	return func(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
+		rs.serve(w, NewRequest(req, p))
+	}
+}
+
+// HTTPHandler adapts the handler to an http.Handler, see the package level HTTPHandler
+func (wr *Writer) HTTPHandler(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) http.Handler {
+	return wr.ParamHandler(nil, handler, preferredType, allowedTypes...)
+}
+
+// ParamHandler adapts the handler to an http.Handler, see the package level ParamHandler
+func (wr *Writer) ParamHandler(params ParamFunc, handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) http.Handler {
+	rs := wr.responder(handler, preferredType, allowedTypes...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := NewRequest(r, patternParams(r))
+		req.paramFunc = params
+
+		rs.serve(w, req)
+	})
+}
+
+func (wr *Writer) responder(handler Handler, preferredType ResponseType, allowedTypes ...ResponseType) *responder {
+	rs := newResponder(handler, preferredType, allowedTypes...)
+	rs.writer = wr
+
+	return rs
+}
+
+// countingWriter records the status code and body size written
+type countingWriter struct {
+	http.ResponseWriter
+
+	code int
+	size int
+}
+
+func (cw *countingWriter) WriteHeader(code int) {
+	if cw.code == 0 {
+		cw.code = code
+	}
+
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+
+	n, err := cw.ResponseWriter.Write(b)
+	cw.size += n
+
+	return n, err
+}
+
+func (cw *countingWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// responseRecorder copies the status code, headers and body written, except the request ID
+type responseRecorder struct {
+	http.ResponseWriter
+
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.code == 0 {
+		rr.code = code
+		rr.header = rr.Header().Clone()
+		rr.header.Del(RequestIDHeader)
+	}
+
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.code == 0 {
+		rr.WriteHeader(http.StatusOK)
+	}
+
+	rr.body.Write(b)
+
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// storedHeader returns the recorded headers without the values added by middleware for this request,
+// e.g. the CORS headers of its origin. Other values of the same header, like a Vary of the reply, are kept.
+func storedHeader(recorded, added http.Header) http.Header {
+	h := recorded.Clone()
+
+	for k, values := range added {
+		var kept []string
+
+		if k == "Vary" {
+			kept = withoutVary(h[k], added)
+		} else {
+			for _, v := range h[k] {
+				if !slices.Contains(values, v) {
+					kept = append(kept, v)
+				}
+			}
+		}
+
+		if len(kept) > 0 {
+			h[k] = kept
+		} else {
+			delete(h, k)
+		}
+	}
+
+	return h
+}
+
+// withoutVary returns the listed headers which are not listed by the added headers
+func withoutVary(values []string, added http.Header) []string {
+	var kept []string
+
+	for _, v := range values {
+		for _, listed := range strings.Split(v, ",") {
+			if listed = strings.TrimSpace(listed); len(listed) > 0 && !varies(added, listed) {
+				kept = append(kept, listed)
+			}
+		}
+	}
+
+	return kept
+}
+
+// writeStoredHeader sets the headers of a stored response, headers added by middleware for this
+// request take precedence and the Vary header lists the headers of both
+func writeStoredHeader(w http.ResponseWriter, stored, added http.Header) {
+	for _, h := range []http.Header{stored, added} {
+		for k, v := range h {
+			if k != "Vary" {
+				w.Header()[k] = v
+			}
+		}
+	}
+
+	addVary(w.Header(), added.Values("Vary")...)
+	addVary(w.Header(), stored.Values("Vary")...)
+}